@@ -11,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // ノード先頭の位置
+	End() token.Position // ノード末尾の直後の位置
 }
 
 // Statement 文。Nodeの集まり
@@ -39,6 +41,8 @@ type Identifier struct {
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 
 // IntegerLiteral 数値リテラル。Token1つからなり、Valueを持つ
 // Expression I/F
@@ -54,6 +58,25 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
+
+// FloatLiteral 浮動小数点数リテラル。Token1つからなり、Valueを持つ
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
 
 type Boolean struct {
 	Token token.Token
@@ -63,6 +86,8 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 
 // PrefixExpression 前置演算子式
 // Expression I/F
@@ -78,6 +103,8 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position  { return endOf(pe.Right, pe.Token.End) }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -102,6 +129,8 @@ type InfixExpression struct {
 
 func (in *InfixExpression) expressionNode()      {}
 func (in *InfixExpression) TokenLiteral() string { return in.Token.Literal }
+func (in *InfixExpression) Pos() token.Position  { return posOf(in.Left, in.Token.Pos) }
+func (in *InfixExpression) End() token.Position  { return endOf(in.Right, in.Token.End) }
 func (in *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -112,6 +141,33 @@ func (in *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression 代入式。Operatorは = または複合代入演算子（+= など）
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type AssignExpression struct {
+	Token    token.Token // 代入演算子トークン。ex. =
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return posOf(ae.Target, ae.Token.Pos) }
+func (ae *AssignExpression) End() token.Position  { return endOf(ae.Value, ae.Token.End) }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 // IfExpression if式
 // Expression I/F
 // 	expressionNode()
@@ -127,6 +183,16 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return endOf(ie.Condition, ie.Token.End)
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -151,27 +217,65 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      token.Token // fnトークン
 	Parameters []*Identifier
+	Defaults   []Expression // Parametersと同じ添字の既定値。既定値のない仮引数はnil。どの仮引数にもなければ空
+	Rest       *Identifier  // 残りの引数を配列で受け取る仮引数（...rest）。なければnil
 	Body       *BlockStatement
+	Name       string // let文で束縛された名前。無名関数なら空
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParametersString(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(") ")
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// ParametersString 既定値と残りの引数を含む仮引数の並びを文字列で返す
+func ParametersString(parameters []*Identifier, defaults []Expression, rest *Identifier) string {
+	params := []string{}
+	for i, p := range parameters {
+		if i < len(defaults) && defaults[i] != nil {
+			params = append(params, p.String()+" = "+defaults[i].String())
+			continue
+		}
+		params = append(params, p.String())
+	}
+	if rest != nil {
+		params = append(params, "..."+rest.String())
+	}
+	return strings.Join(params, ", ")
+}
+
+// SpreadExpression 配列を展開して要素を並べる式（...arr）。呼び出しの引数と配列リテラルの要素にのみ書ける
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type SpreadExpression struct {
+	Token token.Token // ...トークン
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadExpression) End() token.Position  { return endOf(se.Value, se.Token.End) }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
 // CallExpression 呼び出し式
 // Expression I/F
 // 	expressionNode()
@@ -182,10 +286,13 @@ type CallExpression struct {
 	Token     token.Token // ( トークン
 	Function  Expression  // Identifier または FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Token // ) トークン
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return posOf(ce.Function, ce.Token.Pos) }
+func (ce *CallExpression) End() token.Position  { return closingEnd(ce.Rparen, ce.Token.End) }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -210,10 +317,13 @@ func (ce *CallExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token // { トークン
 	Statements []Statement
+	Rbrace     token.Token // } トークン
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position  { return closingEnd(bs.Rbrace, bs.Token.End) }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -229,18 +339,37 @@ func (bs *BlockStatement) String() string {
 // 	TokenLiteral()
 // 	String()
 type LetStatement struct {
-	Token token.Token // letトークン
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // letトークン
+	Name    *Identifier
+	Pattern Pattern // 分割代入（let [a, b] = xs）の束縛先。Nameとどちらか一方だけを持つ
+	Value   Expression
+	Doc     *CommentGroup // 直前のドキュメントコメント。なければnil
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	if ls.Pattern != nil {
+		return ls.Pattern.End()
+	}
+	return ls.Token.End
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -264,6 +393,8 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position  { return endOf(rs.ReturnValue, rs.Token.End) }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -277,6 +408,105 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement while文。Conditionが真である間Bodyを繰り返す
+// Statement I/F
+// 	statementNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type WhileStatement struct {
+	Token     token.Token // whileトークン
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return endOf(ws.Condition, ws.Token.End)
+}
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement for文。Iterableの要素を順にVariableに束縛してBodyを繰り返す
+// Statement I/F
+// 	statementNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type ForStatement struct {
+	Token    token.Token // forトークン
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return endOf(fs.Iterable, fs.Token.End)
+}
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement break文。もっとも内側のループを抜ける
+// Statement I/F
+// 	statementNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type BreakStatement struct {
+	Token token.Token // breakトークン
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position  { return bs.Token.End }
+func (bs *BreakStatement) String() string       { return "break;" }
+
+// ContinueStatement continue文。もっとも内側のループの次の繰り返しに進む
+// Statement I/F
+// 	statementNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type ContinueStatement struct {
+	Token token.Token // continueトークン
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position  { return cs.Token.End }
+func (cs *ContinueStatement) String() string       { return "continue;" }
+
 // ExpressionStatement 式文。
 // Statement I/F
 // 	statementNode()
@@ -290,6 +520,8 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return posOf(es.Expression, es.Token.Pos) }
+func (es *ExpressionStatement) End() token.Position  { return endOf(es.Expression, es.Token.End) }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -314,6 +546,22 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+// Pos 先頭の文の位置を返す
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+// End 末尾の文の末尾の位置を返す
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // Programが持つ文すべてのStringを返す
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -333,14 +581,19 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
 
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // '[' トークン
 	Elements []Expression
+	Rbracket token.Token // ']' トークン
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position  { return closingEnd(al.Rbracket, al.Token.End) }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -356,13 +609,16 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token // '[' トークン
-	Left  Expression
-	Index Expression
+	Token    token.Token // '[' トークン
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // ']' トークン
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return posOf(ie.Left, ie.Token.Pos) }
+func (ie *IndexExpression) End() token.Position  { return closingEnd(ie.Rbracket, ie.Token.End) }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -374,13 +630,51 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression スライス式（a[start:stop]）。StartとStopは省略するとnil
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type SliceExpression struct {
+	Token    token.Token // '[' トークン
+	Left     Expression
+	Start    Expression
+	Stop     Expression
+	Rbracket token.Token // ']' トークン
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position  { return posOf(se.Left, se.Token.Pos) }
+func (se *SliceExpression) End() token.Position  { return closingEnd(se.Rbracket, se.Token.End) }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.Stop != nil {
+		out.WriteString(se.Stop.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
 type HashLiteral struct {
-	Token token.Token // '{' トークン
-	Pairs map[Expression]Expression
+	Token  token.Token // '{' トークン
+	Pairs  map[Expression]Expression
+	Rbrace token.Token // '}' トークン
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return closingEnd(hl.Rbrace, hl.Token.End) }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
@@ -403,6 +697,13 @@ type MacroLiteral struct {
 
 func (ml *MacroLiteral) expressionNode()      {}
 func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+	return ml.Token.End
+}
 func (ml *MacroLiteral) String() string {
 	var out bytes.Buffer
 
@@ -418,3 +719,116 @@ func (ml *MacroLiteral) String() string {
 	out.WriteString(ml.Body.String())
 	return out.String()
 }
+
+// Comment // または /* */ のコメント
+type Comment struct {
+	Token token.Token // COMMENTトークン
+}
+
+func (c *Comment) Pos() token.Position { return c.Token.Pos }
+func (c *Comment) End() token.Position { return c.Token.End }
+
+// Text コメント記号を除いた本文を返す
+func (c *Comment) Text() string {
+	text := c.Token.Literal
+	if strings.HasPrefix(text, "//") {
+		return strings.TrimPrefix(text[2:], " ")
+	}
+
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "*")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// CommentGroup 空行を挟まずに連続するコメントのまとまり
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) Pos() token.Position { return g.List[0].Pos() }
+func (g *CommentGroup) End() token.Position { return g.List[len(g.List)-1].End() }
+
+// Text コメント記号を除いた本文を改行でつないで返す
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+
+	lines := []string{}
+	for _, c := range g.List {
+		lines = append(lines, c.Text())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BadExpression 構文エラーにより解析できなかった式。
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type BadExpression struct {
+	Token token.Token    // 解析に失敗した式の先頭のトークン
+	To    token.Position // 解析を諦めた位置
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+func (be *BadExpression) Pos() token.Position  { return be.Token.Pos }
+func (be *BadExpression) End() token.Position {
+	if be.To.IsValid() {
+		return be.To
+	}
+	return be.Token.End
+}
+
+// BadStatement 構文エラーにより解析できなかった文。
+// Statement I/F
+// 	statementNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type BadStatement struct {
+	Token token.Token    // 解析に失敗した文の先頭のトークン
+	To    token.Position // 読み飛ばした末尾の位置
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+func (bs *BadStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BadStatement) End() token.Position {
+	if bs.To.IsValid() {
+		return bs.To
+	}
+	return bs.Token.End
+}
+
+// nがnilでなければその先頭の位置を、nilであればfallbackを返す
+func posOf(n Node, fallback token.Position) token.Position {
+	if n == nil {
+		return fallback
+	}
+	return n.Pos()
+}
+
+// nがnilでなければその末尾の位置を、nilであればfallbackを返す
+func endOf(n Node, fallback token.Position) token.Position {
+	if n == nil {
+		return fallback
+	}
+	return n.End()
+}
+
+// 閉じ括弧のトークンを読めていればその末尾の位置を、読めていなければfallbackを返す
+func closingEnd(closing token.Token, fallback token.Position) token.Position {
+	if !closing.End.IsValid() {
+		return fallback
+	}
+	return closing.End
+}
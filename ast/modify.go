@@ -13,30 +13,62 @@ func Modify(node Node, modifier ModifierFunc) Node {
 	case *InfixExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *AssignExpression:
+		node.Target, _ = Modify(node.Target, modifier).(Expression)
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *PrefixExpression:
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 	case *IndexExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
+	case *SliceExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		if node.Start != nil {
+			node.Start, _ = Modify(node.Start, modifier).(Expression)
+		}
+		if node.Stop != nil {
+			node.Stop, _ = Modify(node.Stop, modifier).(Expression)
+		}
 	case *IfExpression:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
 		if node.Alternative != nil {
 			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
 		}
+	case *MatchExpression:
+		node.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		for _, arm := range node.Arms {
+			if arm.Guard != nil {
+				arm.Guard, _ = Modify(arm.Guard, modifier).(Expression)
+			}
+			arm.Body = Modify(arm.Body, modifier)
+		}
 	case *BlockStatement:
 		for i := range node.Statements {
 			node.Statements[i], _ = Modify(node.Statements[i], modifier).(Statement)
 		}
 	case *ReturnStatement:
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+	case *WhileStatement:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *ForStatement:
+		node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 	case *LetStatement:
 		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *FunctionLiteral:
 		for i := range node.Parameters {
 			node.Parameters[i] = Modify(node.Parameters[i], modifier).(*Identifier)
 		}
+		for i := range node.Defaults {
+			if node.Defaults[i] != nil {
+				node.Defaults[i], _ = Modify(node.Defaults[i], modifier).(Expression)
+			}
+		}
 		node.Body = Modify(node.Body, modifier).(*BlockStatement)
+	case *SpreadExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *ArrayLiteral:
 		for i := range node.Elements {
 			node.Elements[i] = Modify(node.Elements[i], modifier).(Expression)
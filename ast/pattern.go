@@ -0,0 +1,187 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ktny/monkey/token"
+)
+
+// Pattern match式の腕に書くパターン。値の形を検査し、名前を束縛する
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern _ のパターン。どの値にも一致し、何も束縛しない
+// Pattern I/F
+// 	patternNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type WildcardPattern struct {
+	Token token.Token // _ トークン
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos }
+func (wp *WildcardPattern) End() token.Position  { return wp.Token.End }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// IdentifierPattern 識別子のパターン。どの値にも一致し、値を名前に束縛する
+// Pattern I/F
+// 	patternNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type IdentifierPattern struct {
+	Name *Identifier
+}
+
+func (ip *IdentifierPattern) patternNode()         {}
+func (ip *IdentifierPattern) TokenLiteral() string { return ip.Name.TokenLiteral() }
+func (ip *IdentifierPattern) Pos() token.Position  { return ip.Name.Pos() }
+func (ip *IdentifierPattern) End() token.Position  { return ip.Name.End() }
+func (ip *IdentifierPattern) String() string       { return ip.Name.String() }
+
+// LiteralPattern 数値、文字列、真偽値のリテラルのパターン。等しい値に一致する
+// Pattern I/F
+// 	patternNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type LiteralPattern struct {
+	Value Expression // リテラル。負の数は前置演算子式になる
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) End() token.Position  { return lp.Value.End() }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern 配列のパターン（[a, b, ...rest]）
+// Restがなければ要素数が等しい配列に、あればElements以上の要素数の配列に一致する
+// Pattern I/F
+// 	patternNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type ArrayPattern struct {
+	Token    token.Token // '[' トークン
+	Elements []Pattern
+	Rest     Pattern // 残りの要素を配列で受け取るパターン。なければnil
+	Rbracket token.Token
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) End() token.Position  { return closingEnd(ap.Rbracket, ap.Token.End) }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+// HashPattern ハッシュのパターン（{"key": pattern, name}）
+// すべてのキーを持つハッシュに一致する。nameはキー"name"の値をnameに束縛する省略形
+// Pattern I/F
+// 	patternNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type HashPattern struct {
+	Token  token.Token // '{' トークン
+	Pairs  []*HashPatternPair
+	Rbrace token.Token
+}
+
+// HashPatternPair ハッシュのパターンのキーと値のパターンの組
+type HashPatternPair struct {
+	Key   Expression // キーのリテラル
+	Value Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) End() token.Position  { return closingEnd(hp.Rbrace, hp.Token.End) }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// MatchExpression match式。Subjectを評価し、最初に一致した腕の本体を評価する
+// Expression I/F
+// 	expressionNode()
+// Node I/F
+// 	TokenLiteral()
+// 	String()
+type MatchExpression struct {
+	Token   token.Token // matchトークン
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token
+}
+
+// MatchArm match式の腕（pattern if guard => body）
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // パターンに一致した後に検査する条件。なければnil
+	Body    Node       // 式またはブロック文
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) End() token.Position  { return closingEnd(me.Rbrace, me.Token.End) }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
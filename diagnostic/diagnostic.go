@@ -0,0 +1,216 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ktny/monkey/token"
+)
+
+// Severity 診断の重大度
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic 構文解析や評価で発生したエラーの構造化された情報
+type Diagnostic struct {
+	Severity Severity
+	Code     string         // エラーコード。ex. E0001。なければ空文字
+	Message  string         // エラーメッセージ
+	Pos      token.Position // 対象範囲の先頭の位置
+	End      token.Position // 対象範囲の末尾の直後の位置
+	Notes    []string       // 補足説明
+	Hints    []string       // 修正のヒント
+}
+
+// Error 位置情報付きのエラーメッセージを返す
+func (d Diagnostic) Error() string {
+	if d.Pos.IsValid() {
+		return d.Pos.String() + ": " + d.Message
+	}
+	return d.Message
+}
+
+// Render 診断をrustc風の形式でoutに書き出す。srcは診断の位置が指すソースコードで、空であればソース行は表示しない
+//
+// 	error[E0001]: expected next token to be ), got ; instead
+// 	 --> main.mk:1:14
+// 	  |
+// 	1 | let x = (1 + 2;
+// 	  |               ^
+// 	  = hint: ...
+func Render(out io.Writer, src string, diags []Diagnostic) {
+	lines := splitLines(src)
+
+	for i, d := range diags {
+		if i > 0 {
+			io.WriteString(out, "\n")
+		}
+		renderOne(out, lines, d)
+	}
+}
+
+// RenderFiles 診断をRenderと同じ形式でoutに書き出す。ソース行は診断の位置のファイル名でfilesから引く
+// filesにないファイルを指す診断はソース行を表示しない。REPLのように複数の入力にまたがる診断に使う
+func RenderFiles(out io.Writer, files map[string]string, diags []Diagnostic) {
+	for i, d := range diags {
+		if i > 0 {
+			io.WriteString(out, "\n")
+		}
+		renderOne(out, splitLines(files[d.Pos.Filename]), d)
+	}
+}
+
+func splitLines(src string) []string {
+	if src == "" {
+		return nil
+	}
+	return strings.Split(src, "\n")
+}
+
+func renderOne(out io.Writer, lines []string, d Diagnostic) {
+	header := d.Severity.String()
+	if d.Code != "" {
+		header += "[" + d.Code + "]"
+	}
+	fmt.Fprintf(out, "%s: %s\n", header, d.Message)
+
+	gutter := strings.Repeat(" ", len(fmt.Sprint(d.Pos.Line)))
+
+	if d.Pos.IsValid() {
+		fmt.Fprintf(out, "%s--> %s\n", gutter, d.Pos)
+	}
+
+	if d.Pos.IsValid() && d.Pos.Line <= len(lines) {
+		line := strings.TrimRight(lines[d.Pos.Line-1], "\r")
+		fmt.Fprintf(out, "%s |\n", gutter)
+		fmt.Fprintf(out, "%d | %s\n", d.Pos.Line, line)
+		fmt.Fprintf(out, "%s | %s\n", gutter, underline(line, d.Pos, d.End))
+	}
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(out, "%s = note: %s\n", gutter, note)
+	}
+	for _, hint := range d.Hints {
+		fmt.Fprintf(out, "%s = hint: %s\n", gutter, hint)
+	}
+}
+
+// 行中のposからendまでを指すキャレットを返す。タブはそのまま残して表示位置を揃える
+// 列は文字（コードポイント）単位で数え、全角文字の下には2桁分の空白とキャレットを置く
+func underline(line string, pos, end token.Position) string {
+	runes := []rune(line)
+
+	start := pos.Column - 1
+	if start > len(runes) {
+		start = len(runes)
+	}
+
+	var out strings.Builder
+	for _, ch := range runes[:start] {
+		if ch == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteString(strings.Repeat(" ", displayWidth(ch)))
+		}
+	}
+
+	stop := len(runes)
+	if end.Line == pos.Line && end.Column-1 < stop {
+		stop = end.Column - 1
+	}
+	if stop < start {
+		stop = start
+	}
+
+	width := 0
+	for _, ch := range runes[start:stop] {
+		width += displayWidth(ch)
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	out.WriteString(strings.Repeat("^", width))
+	return out.String()
+}
+
+// 端末で文字chが占める桁数を返す。東アジアの全角文字と絵文字は2桁、それ以外は1桁とみなす
+func displayWidth(ch rune) int {
+	switch {
+	case ch >= 0x1100 && ch <= 0x115F, // ハングル字母
+		ch >= 0x2E80 && ch <= 0x303E, // CJK部首、記号
+		ch >= 0x3041 && ch <= 0x33FF, // ひらがな、カタカナ、CJK互換
+		ch >= 0x3400 && ch <= 0x4DBF, // CJK統合漢字拡張A
+		ch >= 0x4E00 && ch <= 0x9FFF, // CJK統合漢字
+		ch >= 0xA000 && ch <= 0xA4CF, // イ文字
+		ch >= 0xAC00 && ch <= 0xD7A3, // ハングル音節
+		ch >= 0xF900 && ch <= 0xFAFF, // CJK互換漢字
+		ch >= 0xFE30 && ch <= 0xFE4F, // CJK互換形
+		ch >= 0xFF00 && ch <= 0xFF60, // 全角英数、記号
+		ch >= 0xFFE0 && ch <= 0xFFE6, // 全角記号
+		ch >= 0x1F300 && ch <= 0x1F64F, // 絵文字
+		ch >= 0x1F900 && ch <= 0x1F9FF, // 絵文字
+		ch >= 0x20000 && ch <= 0x3FFFD: // CJK統合漢字拡張B以降
+		return 2
+	}
+	return 1
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+type jsonDiagnostic struct {
+	Severity string       `json:"severity"`
+	Code     string       `json:"code,omitempty"`
+	Message  string       `json:"message"`
+	File     string       `json:"file,omitempty"`
+	Start    jsonPosition `json:"start"`
+	End      jsonPosition `json:"end"`
+	Notes    []string     `json:"notes,omitempty"`
+	Hints    []string     `json:"hints,omitempty"`
+}
+
+// RenderJSON 診断をエディタやCIで扱いやすいJSON配列としてoutに書き出す
+func RenderJSON(out io.Writer, diags []Diagnostic) error {
+	list := []jsonDiagnostic{}
+
+	for _, d := range diags {
+		list = append(list, jsonDiagnostic{
+			Severity: d.Severity.String(),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.Pos.Filename,
+			Start:    jsonPosition{Line: d.Pos.Line, Column: d.Pos.Column, Offset: d.Pos.Offset},
+			End:      jsonPosition{Line: d.End.Line, Column: d.End.Column, Offset: d.End.Offset},
+			Notes:    d.Notes,
+			Hints:    d.Hints,
+		})
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
@@ -0,0 +1,176 @@
+package diagnostic
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ktny/monkey/token"
+)
+
+func TestRender(t *testing.T) {
+	src := "let x = 1;\nlet y = (x + 2;\n\tfoo(bar);"
+	diags := []Diagnostic{
+		{
+			Severity: Error,
+			Code:     "E0001",
+			Message:  "expected next token to be ), got ; instead",
+			Pos:      token.Position{Filename: "main.mk", Line: 2, Column: 15},
+			End:      token.Position{Filename: "main.mk", Line: 2, Column: 16},
+		},
+		{
+			Severity: Error,
+			Message:  "identifier not found: bar",
+			Pos:      token.Position{Line: 3, Column: 6},
+			End:      token.Position{Line: 3, Column: 9},
+			Notes:    []string{"bar is not defined"},
+			Hints:    []string{"define bar with let"},
+		},
+	}
+
+	expected := `error[E0001]: expected next token to be ), got ; instead
+ --> main.mk:2:15
+  |
+2 | let y = (x + 2;
+  |               ^
+
+error: identifier not found: bar
+ --> 3:6
+  |
+3 | 	foo(bar);
+  | 	    ^^^
+  = note: bar is not defined
+  = hint: define bar with let
+`
+
+	var out bytes.Buffer
+	Render(&out, src, diags)
+
+	if out.String() != expected {
+		t.Errorf("Render wrong.\nexpected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRenderUnicode(t *testing.T) {
+	src := `let 名前 = "日本" + 1;`
+	diags := []Diagnostic{
+		{
+			Severity: Error,
+			Message:  "type mismatch: STRING + INTEGER",
+			Pos:      token.Position{Line: 1, Column: 10},
+			End:      token.Position{Line: 1, Column: 18},
+		},
+		{
+			Severity: Error,
+			Message:  "identifier not found: 名前",
+			Pos:      token.Position{Line: 1, Column: 5},
+			End:      token.Position{Line: 1, Column: 7},
+		},
+	}
+
+	expected := `error: type mismatch: STRING + INTEGER
+ --> 1:10
+  |
+1 | let 名前 = "日本" + 1;
+  |            ^^^^^^^^^^
+
+error: identifier not found: 名前
+ --> 1:5
+  |
+1 | let 名前 = "日本" + 1;
+  |     ^^^^
+`
+
+	var out bytes.Buffer
+	Render(&out, src, diags)
+
+	if out.String() != expected {
+		t.Errorf("Render wrong.\nexpected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRenderWithoutSource(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: Error, Message: "something went wrong"},
+		{Severity: Error, Message: "identifier not found: x", Pos: token.Position{Filename: "<stdin>", Line: 1, Column: 1}},
+	}
+
+	var out bytes.Buffer
+	Render(&out, "", diags)
+
+	expected := "error: something went wrong\n\nerror: identifier not found: x\n --> <stdin>:1:1\n"
+	if out.String() != expected {
+		t.Errorf("Render wrong. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestRenderFiles(t *testing.T) {
+	files := map[string]string{
+		"<repl:1>": "let f = fn(x) {\n  x / 0\n};\n",
+		"<repl:2>": "f(1)\n",
+	}
+	diags := []Diagnostic{
+		{
+			Severity: Error,
+			Message:  "division by zero",
+			Pos:      token.Position{Filename: "<repl:1>", Offset: 18, Line: 2, Column: 3},
+			End:      token.Position{Filename: "<repl:1>", Offset: 23, Line: 2, Column: 8},
+			Notes:    []string{"in f called at <repl:2>:1:1"},
+		},
+		{Severity: Error, Message: "identifier not found: y", Pos: token.Position{Filename: "<repl:3>", Line: 1, Column: 1}},
+	}
+
+	var out bytes.Buffer
+	RenderFiles(&out, files, diags)
+
+	expected := `error: division by zero
+ --> <repl:1>:2:3
+  |
+2 |   x / 0
+  |   ^^^^^
+  = note: in f called at <repl:2>:1:1
+
+error: identifier not found: y
+ --> <repl:3>:1:1
+`
+	if out.String() != expected {
+		t.Errorf("RenderFiles wrong. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	diags := []Diagnostic{
+		{
+			Severity: Error,
+			Code:     "E0002",
+			Message:  "no prefix parse function for ; found",
+			Pos:      token.Position{Filename: "main.mk", Offset: 12, Line: 1, Column: 13},
+			End:      token.Position{Filename: "main.mk", Offset: 13, Line: 1, Column: 14},
+			Hints:    []string{"an expression was expected here"},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := RenderJSON(&out, diags); err != nil {
+		t.Fatalf("RenderJSON returned error: %s", err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n%s", err, out.String())
+	}
+
+	if len(decoded) != 1 {
+		t.Fatalf("wrong number of diagnostics. got=%d", len(decoded))
+	}
+
+	d := decoded[0]
+	if d["severity"] != "error" || d["code"] != "E0002" || d["file"] != "main.mk" {
+		t.Errorf("wrong diagnostic header. got=%v", d)
+	}
+
+	start := d["start"].(map[string]interface{})
+	if start["line"] != 1.0 || start["column"] != 13.0 || start["offset"] != 12.0 {
+		t.Errorf("wrong start position. got=%v", start)
+	}
+}
@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"errors"
+
+	"github.com/ktny/monkey/object"
+)
+
+// ErrAllocLimitExceeded 確保した配列、文字列、ハッシュの大きさの合計がOptions.MaxAllocBytesを超えたことを表す。打ち切りのエラーのCauseになる
+var ErrAllocLimitExceeded = errors.New("allocation limit exceeded")
+
+// 確保する大きさの見積もりに使うバイト数。Goの実際のメモリ配置とは一致しない
+const (
+	objectOverhead = 16 // オブジェクト1つあたり
+	elementSize    = 16 // 配列の要素1つ（インターフェース値）
+	hashPairSize   = 64 // ハッシュの組1つ（キー、HashPair、マップの管理領域）
+)
+
+func stringSize(bytes int) int64 { return objectOverhead + int64(bytes) }
+func arraySize(length int) int64 { return objectOverhead + elementSize*int64(length) }
+func hashSize(pairs int) int64   { return objectOverhead + hashPairSize*int64(pairs) }
+
+// 新しい配列を作って返す組み込み関数と、引数から見積もった作る配列の大きさ
+// 引数が組み込み関数の受け付けない形であれば0を返し、エラーは組み込み関数に任せる
+var allocatingBuiltins = map[*object.Builtin]func(args []object.Object) int64{
+	builtins["rest"]: func(args []object.Object) int64 {
+		if len(args) != 1 {
+			return 0
+		}
+		if array, ok := args[0].(*object.Array); ok && len(array.Elements) > 0 {
+			return arraySize(len(array.Elements) - 1)
+		}
+		return 0
+	},
+	builtins["push"]: func(args []object.Object) int64 {
+		if len(args) != 2 {
+			return 0
+		}
+		if array, ok := args[0].(*object.Array); ok {
+			return arraySize(len(array.Elements) + 1)
+		}
+		return 0
+	},
+}
+
+// sizeバイトを確保したものとして数える。合計がOptions.MaxAllocBytesを超えたら評価を打ち切り、理由のエラーを返す
+// 確保した量は解放されても減らさない。値を作る前に呼び、上限を超える値は作らない
+// 文字列の添字とfor文で取り出す1文字の文字列は数えない
+func (e *evaluator) allocate(size int64) *object.Error {
+	if e.interrupted != nil {
+		return e.interrupted
+	}
+
+	e.allocated += size
+	if e.opts.MaxAllocBytes > 0 && e.allocated > e.opts.MaxAllocBytes {
+		e.interrupted = newError("evaluation exceeded the allocation limit of %d bytes", e.opts.MaxAllocBytes)
+		e.interrupted.Cause = ErrAllocLimitExceeded
+	}
+	return e.interrupted
+}
+
+// 組み込み関数を呼び出す前に、新しく作る値の大きさを確保した量に数える
+func (e *evaluator) allocateForBuiltin(fn *object.Builtin, args []object.Object) *object.Error {
+	size, ok := allocatingBuiltins[fn]
+	if !ok {
+		return nil
+	}
+	return e.allocate(size(args))
+}
@@ -2,6 +2,10 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/ktny/monkey/object"
 )
@@ -10,23 +14,39 @@ var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return wrongNumberOfArguments("len", len(args), 1)
 			}
 
 			switch arg := args[0].(type) {
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				// 文字（コードポイント）の数。UTF-8を先頭から数えるので、文字列の長さに比例した時間がかかる
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
+	"bytes": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongNumberOfArguments("bytes", len(args), 1)
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `bytes` must be STRING, got %s", args[0].Type())
+			}
+
+			return &object.Integer{Value: int64(len(str.Value))}
+		},
+	},
 	"first": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return wrongNumberOfArguments("first", len(args), 1)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
@@ -43,7 +63,7 @@ var builtins = map[string]*object.Builtin{
 	"last": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return wrongNumberOfArguments("last", len(args), 1)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
@@ -61,7 +81,7 @@ var builtins = map[string]*object.Builtin{
 	"rest": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return wrongNumberOfArguments("rest", len(args), 1)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
@@ -81,7 +101,7 @@ var builtins = map[string]*object.Builtin{
 	"push": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2", len(args))
+				return wrongNumberOfArguments("push", len(args), 2)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
@@ -97,6 +117,85 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"int": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongNumberOfArguments("int", len(args), 1)
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				// 小数部は切り捨てる
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) || math.Abs(arg.Value) >= 1<<63 {
+					return newError("cannot convert %s to INTEGER", arg.Inspect())
+				}
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.String:
+				value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+				if err != nil {
+					return newError("could not parse %q as integer", arg.Value)
+				}
+				return &object.Integer{Value: value}
+			default:
+				return newError("argument to `int` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"float": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongNumberOfArguments("float", len(args), 1)
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+			case *object.Float:
+				return arg
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("could not parse %q as float", arg.Value)
+				}
+				return &object.Float{Value: value}
+			default:
+				return newError("argument to `float` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"range": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return arityError("range", len(args), "1 to 3")
+			}
+
+			values := []int64{}
+			for _, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("arguments to `range` must be INTEGER, got %s", arg.Type())
+				}
+				values = append(values, integer.Value)
+			}
+
+			// range(stop), range(start, stop), range(start, stop, step)
+			r := &object.Range{Start: 0, Step: 1}
+			switch len(values) {
+			case 1:
+				r.Stop = values[0]
+			case 2:
+				r.Start, r.Stop = values[0], values[1]
+			case 3:
+				r.Start, r.Stop, r.Step = values[0], values[1], values[2]
+			}
+			if r.Step == 0 {
+				return newError("`range` step must not be zero")
+			}
+			return r
+		},
+	},
 	"puts": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
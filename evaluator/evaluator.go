@@ -1,7 +1,13 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/ktny/monkey/ast"
 	"github.com/ktny/monkey/object"
@@ -11,102 +17,257 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// Options 評価の動作を切り替えるオプション。ゼロ値は既定の動作になる
+type Options struct {
+	// CheckedArithmetic trueなら整数演算のオーバーフローをエラーにする。falseなら2の補数で循環する
+	CheckedArithmetic bool
+	// MaxCallDepth 関数呼び出しのネストの上限。超えると呼び出し履歴を持つエラーになる
+	// 0ならDefaultMaxCallDepth、負なら上限を設けない。末尾呼び出しはネストを深くしない
+	MaxCallDepth int
+	// Context 評価を中断するためのコンテキスト。キャンセルされるか期限を過ぎると評価を打ち切ってエラーを返す。nilなら中断しない
+	Context context.Context
+	// MaxSteps 評価するノードの数の上限。超えると評価を打ち切ってエラーを返す。0なら上限を設けない
+	MaxSteps int64
+	// MaxAllocBytes 評価中に新しく作る配列、文字列、ハッシュの大きさの合計の上限（バイト数の見積もり）
+	// 超えると評価を打ち切ってエラーを返す。0なら上限を設けない
+	MaxAllocBytes int64
+}
+
+// ErrStepLimitExceeded 評価したノードの数がOptions.MaxStepsを超えたことを表す。打ち切りのエラーのCauseになる
+var ErrStepLimitExceeded = errors.New("step limit exceeded")
+
+// DefaultMaxCallDepth Options.MaxCallDepthを指定しない場合の関数呼び出しのネストの上限
+// Goのスタックを使い切ってプロセスが終了する前にエラーにする
+const DefaultMaxCallDepth = 10000
+
+// evaluator 1回の評価で共有する状態を持つ
+type evaluator struct {
+	opts Options
+	// 本体を評価中の関数呼び出しの履歴。最も内側の呼び出しが末尾。空であればreturn文は関数の外にある
+	frames []object.StackFrame
+
+	done      <-chan struct{} // Options.ContextのDone。Contextがなければnil
+	steps     int64           // これまでに評価したノードの数
+	allocated int64           // これまでに作った配列、文字列、ハッシュの大きさの合計
+	// 評価を打ち切った理由のエラー。打ち切った後はどのノードもこのエラーを返す
+	interrupted *object.Error
+}
+
+// Eval ノードを既定のオプションで評価する
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return EvalWithOptions(node, env, Options{})
+}
+
+// EvalWithOptions ノードを指定のオプションで評価する
+func EvalWithOptions(node ast.Node, env *object.Environment, opts Options) object.Object {
+	return newEvaluator(opts).eval(node, env)
+}
+
+func newEvaluator(opts Options) *evaluator {
+	e := &evaluator{opts: opts}
+	if opts.Context != nil {
+		e.done = opts.Context.Done()
+	}
+	return e
+}
+
+// ノードを評価する。返すエラーが位置情報を持たなければ、評価したノードの位置を付与する
+// 評価の前にOptionsのContextとMaxStepsを確かめ、打ち切る場合はノードを評価せずにエラーを返す
+func (e *evaluator) eval(node ast.Node, env *object.Environment) object.Object {
+	var result object.Object
+	if err := e.interrupt(); err != nil {
+		result = err
+	} else {
+		result = e.evalNode(node, env)
+	}
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos, err.End = node.Pos(), node.End()
+	}
+	return result
+}
+
+// 評価を打ち切るべきであれば理由のエラーを返す。呼び出すたびに評価したノードの数を1つ増やす
+// 一度打ち切ったら、エラーを握りつぶす式があっても以降のノードの評価で必ず同じエラーを返す
+func (e *evaluator) interrupt() *object.Error {
+	if e.interrupted != nil {
+		return e.interrupted
+	}
+
+	e.steps++
+	if e.opts.MaxSteps > 0 && e.steps > e.opts.MaxSteps {
+		e.interrupted = newError("evaluation exceeded the step limit of %d", e.opts.MaxSteps)
+		e.interrupted.Cause = ErrStepLimitExceeded
+		return e.interrupted
+	}
+
+	if e.done == nil {
+		return nil
+	}
+	select {
+	case <-e.done:
+		cause := e.opts.Context.Err()
+		if cause == context.DeadlineExceeded {
+			e.interrupted = newError("evaluation timed out")
+		} else {
+			e.interrupted = newError("evaluation cancelled")
+		}
+		e.interrupted.Cause = cause
+	default:
+	}
+	return e.interrupted
+}
+
+// evalFunc 制御構造の本体を評価する関数。e.eval, e.evalTail, e.evalReturnTailのいずれか
+type evalFunc func(node ast.Node, env *object.Environment) object.Object
+
+// return文の値valをReturnValueで包む。エラーと、値の中のreturn文ですでに包まれた値はそのまま返す
+func wrapReturnValue(val object.Object) object.Object {
+	switch val.(type) {
+	case *object.Error, *object.ReturnValue:
+		return val
+	}
+	return &object.ReturnValue{Value: val}
+}
+
+func (e *evaluator) evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// 文
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
-			return val
-		}
-		return &object.ReturnValue{Value: val}
+		// 値が関数の結果に直接届くreturn文はevalTail, evalReturnTailが評価する。それ以外の位置では値をその場で評価する
+		return wrapReturnValue(e.eval(node.ReturnValue, env))
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env, e.eval)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env, e.eval)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.BadStatement:
+		return newError("cannot evaluate statement with syntax errors")
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern == nil {
+			env.Set(node.Name.Value, val)
+		} else if err := e.bindPattern(node.Pattern, val, env); err != nil {
+			return err
+		}
 
 	// 式
+	case *ast.BadExpression:
+		return newError("cannot evaluate expression with syntax errors")
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		// 展開する要素の分はevalExpressionsが並べる前に数える
+		if err := e.allocate(arraySize(len(node.Elements))); err != nil {
+			return err
+		}
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := e.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := e.eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return e.evalSliceExpression(node, env)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, env)
+		}
+		left := e.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return e.evalInfixExpression(node.Operator, left, right)
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env, e.eval)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env, e.eval)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
+	case *ast.SpreadExpression:
+		return newError("spread is only allowed in call arguments and array literals")
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{
+			Name:       node.Name,
+			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       body,
+		}
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "quote" {
-			return quote(node.Arguments[0], env)
+			if len(node.Arguments) != 1 {
+				return wrongNumberOfArguments("quote", len(node.Arguments), 1)
+			}
+			return e.quote(node.Arguments[0], env)
 		}
-		function := Eval(node.Function, env)
+		function := e.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return e.applyFunction(node, function, args)
 	}
 
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -119,15 +280,15 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -142,10 +303,10 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
+func (e *evaluator) evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		if returnValue, ok := result.(*object.ReturnValue); ok {
 			return returnValue.Value
@@ -154,12 +315,14 @@ func evalStatements(stmts []ast.Statement, env *object.Environment) object.Objec
 	return result
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func (e *evaluator) evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return e.evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -176,21 +339,28 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func (e *evaluator) evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if e.opts.CheckedArithmetic && right.Value == math.MinInt64 {
+			return newError("integer overflow: -(%d)", right.Value)
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func (e *evaluator) evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return e.evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
+		return e.evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -202,10 +372,23 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+// ビット反転を評価する。整数にのみ適用できる
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	integer, ok := right.(*object.Integer)
+	if !ok {
+		return newError("unknown operator: ~%s", right.Type())
+	}
+	return &object.Integer{Value: ^integer.Value}
+}
+
+func (e *evaluator) evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if e.opts.CheckedArithmetic && overflows(operator, leftVal, rightVal) {
+		return newError("integer overflow: %d %s %d", leftVal, operator, rightVal)
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: leftVal + rightVal}
@@ -214,11 +397,105 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		// 剰余の符号は左辺に従う（-7 % 3 == -1）
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal << uint64(rightVal)}
+	case ">>":
+		// 算術シフト。負の数は符号が保たれる（-8 >> 1 == -4）
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// 整数演算の結果がint64に収まらないか否かを返す
+func overflows(operator string, left, right int64) bool {
+	switch operator {
+	case "+":
+		return right > 0 && left > math.MaxInt64-right || right < 0 && left < math.MinInt64-right
+	case "-":
+		return right < 0 && left > math.MaxInt64+right || right > 0 && left < math.MinInt64+right
+	case "*":
+		if left == 0 || right == 0 {
+			return false
+		}
+		if left == -1 && right == math.MinInt64 || right == -1 && left == math.MinInt64 {
+			return true
+		}
+		result := left * right
+		return result/right != left
+	case "/":
+		return left == math.MinInt64 && right == -1
+	case "<<":
+		// 左シフトで失われるビットがあればオーバーフローとする
+		if right < 0 || left == 0 {
+			return false
+		}
+		if right >= 64 {
+			return true
+		}
+		return (left<<uint64(right))>>uint64(right) != left
+	}
+	return false
+}
+
+// 整数と浮動小数点数が混在する場合は浮動小数点数として計算する
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -228,12 +505,28 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+// 整数または浮動小数点数か否かを返す
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// 整数または浮動小数点数をfloat64にして返す
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+func (e *evaluator) evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
 	switch operator {
 	case "+":
+		if err := e.allocate(stringSize(len(leftVal) + len(rightVal))); err != nil {
+			return err
+		}
 		return &object.String{Value: leftVal + rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -248,21 +541,372 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+// if式を評価する。分岐のブロックはevalBodyで評価する
+func (e *evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment, evalBody evalFunc) object.Object {
+	condition := e.eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return evalBody(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return evalBody(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+// match式を評価する。パターンに一致しガードが真になった最初の腕の本体を評価し、どの腕にも一致しなければnullを返す
+// パターンで束縛した名前は腕ごとの新しい環境に入るので、外側の変数を上書きしない
+// 腕の本体はevalBodyで評価する
+func (e *evaluator) evalMatchExpression(me *ast.MatchExpression, env *object.Environment, evalBody evalFunc) object.Object {
+	subject := e.eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if err := e.bindPattern(arm.Pattern, subject, armEnv); err != nil {
+			// 評価の打ち切りは一致しなかったことにせず、そのままエラーとして返す
+			if err.Cause != nil || e.interrupted != nil {
+				return err
+			}
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := e.eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		// 空のブロックの本体は値を持たないので、nullにする
+		if result := evalBody(arm.Body, armEnv); result != nil {
+			return result
+		}
+		return NULL
+	}
+
+	return NULL
+}
+
+// valueをpatternに照合し、パターン中の識別子をenvに束縛する。一致しなければ理由と一致しなかった部分のパターンの位置を持つエラーを返す
+// match式では次の腕を試す合図に、let文の分割代入ではそのままエラーとして使う
+// パターン中のリテラルやキーの評価がエラーになった場合（評価の打ち切りなど）は、そのエラーを返す
+func (e *evaluator) bindPattern(pattern ast.Pattern, value object.Object, env *object.Environment) *object.Error {
+	mismatch := func(format string, a ...interface{}) *object.Error {
+		err := newError(format, a...)
+		err.Pos, err.End = pattern.Pos(), pattern.End()
+		return err
+	}
+
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+	case *ast.IdentifierPattern:
+		env.Set(pattern.Name.Value, value)
+		return nil
+	case *ast.LiteralPattern:
+		literal := e.eval(pattern.Value, env)
+		if err, ok := literal.(*object.Error); ok {
+			return err
+		}
+		if !literalEquals(literal, value) {
+			return mismatch("value %s does not match pattern %s", value.Inspect(), pattern)
+		}
+		return nil
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return mismatch("cannot destructure %s with array pattern %s", value.Type(), pattern)
+		}
+		if pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) {
+			return mismatch("array pattern %s expects %d elements, got %d", pattern, len(pattern.Elements), len(array.Elements))
+		}
+		if len(array.Elements) < len(pattern.Elements) {
+			return mismatch("array pattern %s expects at least %d elements, got %d", pattern, len(pattern.Elements), len(array.Elements))
+		}
+		for i, el := range pattern.Elements {
+			if err := e.bindPattern(el, array.Elements[i], env); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			if err := e.allocate(arraySize(len(array.Elements) - len(pattern.Elements))); err != nil {
+				return err
+			}
+			rest := make([]object.Object, len(array.Elements)-len(pattern.Elements))
+			copy(rest, array.Elements[len(pattern.Elements):])
+			return e.bindPattern(pattern.Rest, &object.Array{Elements: rest}, env)
+		}
+		return nil
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return mismatch("cannot destructure %s with hash pattern %s", value.Type(), pattern)
+		}
+		for _, pair := range pattern.Pairs {
+			key := e.eval(pair.Key, env)
+			if err, ok := key.(*object.Error); ok {
+				return err
+			}
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return mismatch("unusable as hash key: %s", key.Type())
+			}
+			found, ok := hash.Pairs[hashKey.HashKey()]
+			if !ok {
+				err := newError("key not found: %s", key.Inspect())
+				err.Pos, err.End = pair.Key.Pos(), pair.Key.End()
+				return err
+			}
+			if err := e.bindPattern(pair.Value, found.Value, env); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return mismatch("unknown pattern: %s", pattern)
+}
+
+// リテラルのパターンの値literalとvalueが等しいか否かを返す。数値は整数と浮動小数点数の間でも値で比べる
+func literalEquals(literal, value object.Object) bool {
+	if isNumber(literal) && isNumber(value) {
+		if l, ok := literal.(*object.Integer); ok {
+			if r, ok := value.(*object.Integer); ok {
+				return l.Value == r.Value
+			}
+		}
+		return toFloat(literal) == toFloat(value)
+	}
+
+	switch literal := literal.(type) {
+	case *object.String:
+		str, ok := value.(*object.String)
+		return ok && str.Value == literal.Value
+	case *object.Boolean:
+		return literal == value
+	}
+	return false
+}
+
+// 代入式を評価して代入した値を返す。複合代入は現在の値と右辺を演算した結果を代入する
+func (e *evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return newError("assignment to undefined variable: %s", target.Value)
+		}
+
+		value := e.evalAssignedValue(node, current, env)
+		if isError(value) {
+			return value
+		}
+
+		env.Assign(target.Value, value)
+		return value
+	case *ast.IndexExpression:
+		left := e.eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := e.eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		return e.evalIndexAssignment(node, left, index, env)
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// 代入する値を評価して返す。複合代入ならcurrentと演算した結果を返す
+func (e *evaluator) evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := e.eval(node.Value, env)
+	if isError(value) || node.Operator == "=" {
+		return value
+	}
+	return e.evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, value)
+}
+
+// 配列の要素またはハッシュの値をその場で書き換える
+func (e *evaluator) evalIndexAssignment(node *ast.AssignExpression, left, index object.Object, env *object.Environment) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		idx := integer.Value
+		if idx < 0 || idx >= int64(len(left.Elements)) {
+			return newError("index out of range: %d (length %d)", idx, len(left.Elements))
+		}
+
+		value := e.evalAssignedValue(node, left.Elements[idx], env)
+		if isError(value) {
+			return value
+		}
+		left.Elements[idx] = value
+		return value
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			pair, ok := left.Pairs[key.HashKey()]
+			if !ok {
+				return newError("key not found: %s", index.Inspect())
+			}
+			current = pair.Value
+		}
+
+		value := e.evalAssignedValue(node, current, env)
+		if isError(value) {
+			return value
+		}
+		if _, ok := left.Pairs[key.HashKey()]; !ok {
+			if err := e.allocate(hashPairSize); err != nil {
+				return err
+			}
+		}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+		return value
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+// while文を評価する。return, エラーで抜けた場合はその結果を、それ以外はnullを返す。本体はevalBodyで評価する
+func (e *evaluator) evalWhileStatement(node *ast.WhileStatement, env *object.Environment, evalBody evalFunc) object.Object {
+	for {
+		condition := e.eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		switch result := evalBody(node.Body, env).(type) {
+		case *object.Break:
+			return NULL
+		case *object.ReturnValue, *object.Error:
+			return result
+		}
+	}
+}
+
+// for文を評価する。return, エラーで抜けた場合はその結果を、それ以外はnullを返す。本体はevalBodyで評価する
+// 要素ごとに新しい環境で変数を束縛するので、クロージャはその回の要素を捕捉する
+func (e *evaluator) evalForStatement(node *ast.ForStatement, env *object.Environment, evalBody evalFunc) object.Object {
+	iterable := e.eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	next, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	for {
+		item, ok := next()
+		if !ok {
+			return NULL
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(node.Variable.Value, item)
+
+		switch result := evalBody(node.Body, loopEnv).(type) {
+		case *object.Break:
+			return NULL
+		case *object.ReturnValue, *object.Error:
+			return result
+		}
+	}
+}
+
+// for文で繰り返す要素を順に返す関数を返す
+// 配列は要素、文字列は1文字ずつの文字列、ハッシュはキーの順に並べたキー、rangeは整数を返す
+func iterate(iterable object.Object) (func() (object.Object, bool), *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		i := 0
+		return func() (object.Object, bool) {
+			if i >= len(iterable.Elements) {
+				return nil, false
+			}
+			i++
+			return iterable.Elements[i-1], true
+		}, nil
+	case *object.String:
+		offset := 0
+		return func() (object.Object, bool) {
+			if offset >= len(iterable.Value) {
+				return nil, false
+			}
+			r, size := utf8.DecodeRuneInString(iterable.Value[offset:])
+			offset += size
+			return &object.String{Value: string(r)}, true
+		}, nil
+	case *object.Hash:
+		pairs := iterable.SortedPairs()
+		i := 0
+		return func() (object.Object, bool) {
+			if i >= len(pairs) {
+				return nil, false
+			}
+			i++
+			return pairs[i-1].Key, true
+		}, nil
+	case *object.Range:
+		length := iterable.Len()
+		var i int64
+		return func() (object.Object, bool) {
+			if i >= length {
+				return nil, false
+			}
+			i++
+			return &object.Integer{Value: iterable.Start + (i-1)*iterable.Step}, true
+		}, nil
+	default:
+		return nil, newError("cannot iterate over %s", iterable.Type())
+	}
+}
+
+// 論理演算子を評価する。左辺で結果が決まる場合は右辺を評価しない
+func (e *evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := e.eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -280,6 +924,18 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// 引数の数が合わない呼び出しのエラーを返す。nameが空なら無名関数とする
+func wrongNumberOfArguments(name string, got, want int) *object.Error {
+	return arityError(name, got, strconv.Itoa(want))
+}
+
+func arityError(name string, got int, want string) *object.Error {
+	if name == "" {
+		return newError("wrong number of arguments to anonymous function: got=%d, want=%s", got, want)
+	}
+	return newError("wrong number of arguments to `%s`: got=%d, want=%s", name, got, want)
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -297,46 +953,176 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		spread, isSpread := exp.(*ast.SpreadExpression)
+		if isSpread {
+			exp = spread.Value
+		}
+
+		evaluated := e.eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+
+		// ...arr は配列の要素を並べる
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			err := newError("cannot spread %s, expected ARRAY", evaluated.Type())
+			err.Pos, err.End = spread.Pos(), spread.End()
+			return []object.Object{err}
+		}
+		if err := e.allocate(elementSize * int64(len(array.Elements))); err != nil {
+			return []object.Object{err}
+		}
+		result = append(result, array.Elements...)
 	}
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// callの位置で関数を呼び出して結果を返す
+// 本体の末尾位置にある呼び出しはGoの再帰ではなくこのループで続けて呼び出すので、末尾再帰はスタックを消費しない
+// 末尾呼び出しは呼び出し履歴に積まず、呼び出し元の履歴を置き換える
+func (e *evaluator) applyFunction(call *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	depth := len(e.frames)
+	defer func() { e.frames = e.frames[:depth] }()
+
+	for tail := false; ; tail = true {
+		if f, ok := fn.(*object.Function); ok {
+			frame := object.StackFrame{Function: f.Name, Pos: call.Pos()}
+			if len(e.frames) > depth {
+				e.frames[depth] = frame
+			} else if e.exceedsCallDepth() {
+				err := newError("maximum call depth exceeded")
+				err.Stack = []object.StackFrame{frame}
+				return err
+			} else {
+				e.frames = append(e.frames, frame)
+			}
+		}
+
+		result := e.callFunction(fn, args)
+
+		if err, ok := result.(*object.Error); ok {
+			// 末尾呼び出し自体のエラー（引数の数の誤りなど）は末尾呼び出しの位置を指す
+			if tail && !err.Pos.IsValid() {
+				err.Pos, err.End = call.Pos(), call.End()
+			}
+			// 関数から抜け出すエラーに呼び出しを積む。外側の呼び出しほど後ろに並ぶ
+			// 末尾位置で組み込み関数を呼び出した場合は、呼び出し元の関数の呼び出しを積む
+			if len(e.frames) > depth {
+				err.Stack = append(err.Stack, e.frames[depth])
+			}
+			return err
+		}
+
+		tc, ok := result.(*tailCall)
+		if !ok {
+			return result
+		}
+		call, fn, args = tc.call, tc.fn, tc.args
+	}
+}
+
+// 関数呼び出しのネストがOptions.MaxCallDepthに達しているか否かを返す
+func (e *evaluator) exceedsCallDepth() bool {
+	limit := e.opts.MaxCallDepth
+	if limit == 0 {
+		limit = DefaultMaxCallDepth
+	}
+	return limit > 0 && len(e.frames) >= limit
+}
+
+// 関数を1回呼び出して結果を返す。本体が末尾位置で関数を呼び出していれば、呼び出さずに*tailCallを返す
+func (e *evaluator) callFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		if err := checkArity(fn, len(args)); err != nil {
+			return err
+		}
+		extendedEnv, err := e.extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+		evaluated := e.evalTail(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
+		if err := e.allocateForBuiltin(fn, args); err != nil {
+			return err
+		}
 		return fn.Fn(args...)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// 引数を束縛した関数の環境を返す
+// 渡されなかった仮引数には既定値を評価して束縛する。既定値は先行する仮引数を参照できる
+func (e *evaluator) extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		value := e.eval(fn.Defaults[paramIdx], env)
+		if isError(value) {
+			return nil, value
+		}
+		env.Set(param.Value, value)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		if err := e.allocate(arraySize(len(rest))); err != nil {
+			return nil, err
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+// 引数の数が関数の仮引数に合うか検査する。合わなければエラーを返す
+func checkArity(fn *object.Function, got int) *object.Error {
+	min, max := len(fn.Parameters), len(fn.Parameters)
+	for i := range fn.Defaults {
+		if fn.Defaults[i] != nil {
+			min = i
+			break
+		}
 	}
 
-	return env
+	switch {
+	case fn.Rest != nil && got >= min:
+		return nil
+	case fn.Rest == nil && min <= got && got <= max:
+		return nil
+	case fn.Rest != nil:
+		return arityError(fn.Name, got, fmt.Sprintf("at least %d", min))
+	case min == max:
+		return arityError(fn.Name, got, strconv.Itoa(min))
+	default:
+		return arityError(fn.Name, got, fmt.Sprintf("%d to %d", min, max))
+	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	// ReturnValueがあればそれを返す
+	// ReturnValueがあればその値を返す
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue
+		return returnValue.Value
 	}
 	// なければ関数のBodyがreturnされる。つまりreturn文が省略された場合は最後のブロックがreturnされる
 	return obj
@@ -346,6 +1132,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -365,11 +1153,115 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+// 文字列のidx番目の文字（コードポイント）を1文字の文字列で返す。範囲外ならnullを返す
+// 先頭から1文字ずつたどるので、idxに比例した時間がかかる
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	value := str.(*object.String).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 {
+		return NULL
+	}
+	offset := runeOffset(value, idx)
+	if offset >= len(value) {
+		return NULL
+	}
+
+	r, _ := utf8.DecodeRuneInString(value[offset:])
+	return &object.String{Value: string(r)}
+}
+
+// 文字列のn番目の文字（コードポイント）が始まるバイト位置を返す。文字数がn以下なら文字列のバイト数を返す
+func runeOffset(s string, n int64) int {
+	offset := 0
+	for ; n > 0 && offset < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// スライス式を評価して配列または文字列の一部を新しいオブジェクトで返す
+// 負の添字は末尾から数え、範囲外の添字は両端に切り詰める。文字列はコードポイント単位で切り出す
+func (e *evaluator) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var length int64
+	switch left := left.(type) {
+	case *object.Array:
+		length = int64(len(left.Elements))
+	case *object.String:
+		length = int64(utf8.RuneCountInString(left.Value))
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	start, err := e.evalSliceIndex(node.Start, 0, length, env)
+	if err != nil {
+		return err
+	}
+	stop, err := e.evalSliceIndex(node.Stop, length, length, env)
+	if err != nil {
+		return err
+	}
+	if stop < start {
+		stop = start
+	}
+
+	if array, ok := left.(*object.Array); ok {
+		if err := e.allocate(arraySize(int(stop - start))); err != nil {
+			return err
+		}
+		elements := make([]object.Object, stop-start)
+		copy(elements, array.Elements[start:stop])
+		return &object.Array{Elements: elements}
+	}
+	value := left.(*object.String).Value
+	begin := runeOffset(value, start)
+	str := value[begin : begin+runeOffset(value[begin:], stop-start)]
+	if err := e.allocate(stringSize(len(str))); err != nil {
+		return err
+	}
+	return &object.String{Value: str}
+}
+
+// スライスの添字を評価して0以上length以下に切り詰めた値を返す。省略されていればdefを返す
+func (e *evaluator) evalSliceIndex(node ast.Expression, def, length int64, env *object.Environment) (int64, object.Object) {
+	if node == nil {
+		return def, nil
+	}
+
+	index := e.eval(node, env)
+	if isError(index) {
+		return 0, index
+	}
+
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return 0, newError("slice index must be INTEGER, got %s", index.Type())
+	}
+
+	idx := integer.Value
+	if idx < 0 {
+		idx += length
+	}
+	switch {
+	case idx < 0:
+		return 0, nil
+	case idx > length:
+		return length, nil
+	}
+	return idx, nil
+}
+
+func (e *evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
 	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := e.eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
@@ -379,7 +1271,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := e.eval(valueNode, env)
 		if isError(value) {
 			return value
 		}
@@ -388,6 +1280,9 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
 
+	if err := e.allocate(hashSize(len(pairs))); err != nil {
+		return err
+	}
 	return &object.Hash{Pairs: pairs}
 }
 
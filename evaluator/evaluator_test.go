@@ -1,7 +1,11 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/ktny/monkey/lexer"
 	"github.com/ktny/monkey/object"
@@ -17,6 +21,45 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+func testEvalWithOptions(input string, opts Options) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return EvalWithOptions(program, env, opts)
+}
+
+// objがexpectedMessageのエラーで、CauseがexpectedCauseか検査する。Causeを持たないエラーはexpectedCauseにnilを渡す
+func testErrorObject(t *testing.T, obj object.Object, expectedMessage string, expectedCause error) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("no error object returned. got=%T(%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expectedMessage {
+		t.Errorf("wrong error message. expected=%q, got=%q", expectedMessage, errObj.Message)
+		return false
+	}
+	if !errors.Is(errObj.Cause, expectedCause) {
+		t.Errorf("wrong error cause for %q. expected=%v, got=%v", expectedMessage, expectedCause, errObj.Cause)
+		return false
+	}
+	return true
+}
+
+// expectedがintならobjがその整数か、stringならそのメッセージとCauseを持つエラーか検査する
+func testIntegerOrErrorObject(t *testing.T, obj object.Object, expected interface{}, expectedCause error) bool {
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+	case string:
+		return testErrorObject(t, obj, expected, expectedCause)
+	}
+	t.Fatalf("unsupported expected value %T(%+v)", expected, expected)
+	return false
+}
+
 func TestEvalIntegerExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -37,6 +80,24 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xff + 0o7 + 0b1 + 1_000", 1263},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"0b1100 & 0b1010", 8},
+		{"0b1100 | 0b1010", 14},
+		{"0b1100 ^ 0b1010", 6},
+		{"~5", -6},
+		{"~-1", 0},
+		{"1 << 10", 1024},
+		{"-8 >> 1", -4},
+		{"-1 >> 63", -1},
+		{"1 << 64", 0},
+		{"1 + 2 << 3", 17},
+		{"0xff & 0x0f | 0x30", 63},
+		// 既定では2の補数で循環する
+		{"9223372036854775807 + 1", math.MinInt64},
+		{"-9223372036854775807 - 2", math.MaxInt64},
 	}
 
 	for _, tt := range tests {
@@ -58,6 +119,45 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"1e3 - 1", 999},
+		{"float(3)", 3},
+		{"float(\"2.5\")", 2.5},
+		{"float(1.25)", 1.25},
+		{"1.0 / 0", math.Inf(1)},
+		{"-1 / 0.0", math.Inf(-1)},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -82,6 +182,16 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"1 <= 1", true},
+		{"1 <= 0", false},
+		{"1 >= 1", true},
+		{"0 >= 1", false},
+		{"1.5 >= 1", true},
+		{"2 <= 1.5", false},
 	}
 
 	for _, tt := range tests {
@@ -103,6 +213,239 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	return true
 }
 
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"-9223372036854775807 - 1", math.MinInt64},
+		{"let min = -9223372036854775807 - 1; -min", "integer overflow: -(-9223372036854775808)"},
+		{"let min = -9223372036854775807 - 1; min / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"let min = -9223372036854775807 - 1; min * -1", "integer overflow: -9223372036854775808 * -1"},
+		{"1 << 63", "integer overflow: 1 << 63"},
+		{"1 << 62", 1 << 62},
+		{"-1 << 63", math.MinInt64},
+		{"3037000499 * 3037000499", 9223372030926249001},
+		{"1 / 0", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOptions(tt.input, Options{CheckedArithmetic: true})
+		testIntegerOrErrorObject(t, evaluated, tt.expected, nil)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	tests := []struct {
+		input        string
+		maxCallDepth int
+		expected     interface{}
+	}{
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5)", 6, 5},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5)", 5, "maximum call depth exceeded"},
+		{"let f = fn(n) { 1 + f(n) }; f(0)", 0, "maximum call depth exceeded"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(20000)", -1, 20000},
+		// 末尾呼び出しはネストを深くしない
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(100)", 2, 0},
+		// 組み込み関数の呼び出しはネストに数えない
+		{"let f = fn() { len([]) }; f()", 1, 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOptions(tt.input, Options{MaxCallDepth: tt.maxCallDepth})
+		testIntegerOrErrorObject(t, evaluated, tt.expected, nil)
+	}
+
+	// エラーは上限を超えた時点の呼び出し履歴を最も内側の呼び出しから持つ
+	input := `let inner = fn(n) { 1 + inner(n + 1) };
+let outer = fn() { 1 + inner(0) };
+outer()`
+	evaluated := testEvalWithOptions(input, Options{MaxCallDepth: 3})
+	if !testErrorObject(t, evaluated, "maximum call depth exceeded", nil) {
+		t.FailNow()
+	}
+	errObj := evaluated.(*object.Error)
+	if errObj.Pos.String() != "1:25" {
+		t.Errorf("wrong error pos. expected=%s, got=%s", "1:25", errObj.Pos)
+	}
+
+	expectedStack := []string{
+		"inner called at 1:25",
+		"inner called at 1:25",
+		"inner called at 2:24",
+		"outer called at 3:1",
+	}
+	if len(errObj.Stack) != len(expectedStack) {
+		t.Fatalf("wrong stack length. expected=%d, got=%d (%v)", len(expectedStack), len(errObj.Stack), errObj.Stack)
+	}
+	for i, frame := range errObj.Stack {
+		if frame.String() != expectedStack[i] {
+			t.Errorf("stack[%d] wrong. expected=%q, got=%q", i, expectedStack[i], frame.String())
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStack []string
+	}{
+		{"1 / 0", nil},
+		{"let f = fn(x) { x / 0 }; f(1)", []string{"f called at 1:26"}},
+		{"let f = fn() { len(1) }; let g = fn() { 1 + f() }; g()", []string{
+			"f called at 1:45",
+			"g called at 1:52",
+		}},
+		{"fn() { 1 / 0 }()", []string{"anonymous function called at 1:1"}},
+		{"let f = fn(n) { if (n == 0) { -true } else { 1 + f(n - 1) } }; f(2)", []string{
+			"f called at 1:50",
+			"f called at 1:50",
+			"f called at 1:64",
+		}},
+		// 末尾呼び出しは呼び出し元のフレームを置き換える
+		{"let f = fn(n) { if (n == 0) { -true } else { f(n - 1) } }; let g = fn() { 1 + f(3) }; g()", []string{
+			"f called at 1:46",
+			"g called at 1:87",
+		}},
+		// 引数の数の誤りは呼び出された関数のフレームを持つ
+		{"let f = fn(x) { x }; let g = fn() { 1 + f() }; g()", []string{
+			"f called at 1:41",
+			"g called at 1:48",
+		}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if len(errObj.Stack) != len(tt.expectedStack) {
+			t.Errorf("wrong stack length for %q. expected=%d, got=%d (%v)", tt.input, len(tt.expectedStack), len(errObj.Stack), errObj.Stack)
+			continue
+		}
+		for i, frame := range errObj.Stack {
+			if frame.String() != tt.expectedStack[i] {
+				t.Errorf("stack[%d] wrong for %q. expected=%q, got=%q", i, tt.input, tt.expectedStack[i], frame.String())
+			}
+		}
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxSteps int64
+		expected interface{}
+	}{
+		{"1 + 2", 0, 3},
+		{"let i = 0; while (i < 10) { i += 1 }; i", 1000, 10},
+		{"while (true) { }", 1000, "evaluation exceeded the step limit of 1000"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", 1000, "evaluation exceeded the step limit of 1000"},
+		// 打ち切った後はガードの偽や配列の中でもエラーを返し続ける
+		{"let f = fn() { f() }; match (1) { n if f() => n, _ => 0 }", 100, "evaluation exceeded the step limit of 100"},
+		{"let f = fn() { f() }; [f(), 1]", 100, "evaluation exceeded the step limit of 100"},
+		// パターンのリテラルを評価する途中で打ち切っても、一致しなかったことにしない
+		{"match (1) { 2 => 0 }", 4, "evaluation exceeded the step limit of 4"},
+		{`match ({"a": 1}) { {"a": 2} => 0 }`, 6, "evaluation exceeded the step limit of 6"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOptions(tt.input, Options{MaxSteps: tt.maxSteps, MaxCallDepth: -1})
+		testIntegerOrErrorObject(t, evaluated, tt.expected, ErrStepLimitExceeded)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		input           string
+		ctx             context.Context
+		expectedMessage string
+		expectedCause   error
+	}{
+		{"1 + 2", cancelled, "evaluation cancelled", context.Canceled},
+		{"while (true) { }", timeout, "evaluation timed out", context.DeadlineExceeded},
+		{"let f = fn(n) { f(n + 1) }; f(0)", timeout, "evaluation timed out", context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOptions(tt.input, Options{Context: tt.ctx, MaxCallDepth: -1})
+		testErrorObject(t, evaluated, tt.expectedMessage, tt.expectedCause)
+	}
+
+	// キャンセルされないコンテキストでは最後まで評価する
+	evaluated := testEvalWithOptions("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)", Options{Context: context.Background()})
+	testIntegerObject(t, evaluated, 0)
+}
+
+func TestAllocLimit(t *testing.T) {
+	tests := []struct {
+		input         string
+		maxAllocBytes int64
+		expected      interface{}
+	}{
+		{"let xs = []; for (i in range(100)) { xs = push(xs, i) }; len(xs)", 0, 100},
+		{"let xs = []; for (i in range(10)) { xs = push(xs, i) }; len(xs)", 10000, 10},
+		{"let xs = []; while (true) { xs = push(xs, 1) }", 10000, "evaluation exceeded the allocation limit of 10000 bytes"},
+		{`let s = "a"; while (true) { s = s + s }`, 10000, "evaluation exceeded the allocation limit of 10000 bytes"},
+		{"let xs = [1, 2, 3]; while (true) { xs = [...xs, ...xs] }", 10000, "evaluation exceeded the allocation limit of 10000 bytes"},
+		{"let h = {}; let i = 0; while (true) { h[i] = i; i += 1 }", 10000, "evaluation exceeded the allocation limit of 10000 bytes"},
+		// rangeは要素を持たないので大きさに数えない
+		{"let xs = range(1000); len(xs)", 100, 1000},
+		{"let f = fn(...rest) { rest }; f(...[0, 1, 2, 3, 4, 5, 6, 7, 8, 9])", 100, "evaluation exceeded the allocation limit of 100 bytes"},
+		{`"abcdefghij"[0:]`, 20, "evaluation exceeded the allocation limit of 20 bytes"},
+		// 上限を超える配列は作る前に打ち切る
+		{"[...[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]]", 210, "evaluation exceeded the allocation limit of 210 bytes"},
+		{"[...[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]]; 0", 400, 0},
+		{"push([0, 1, 2, 3, 4, 5, 6, 7], 8)", 200, "evaluation exceeded the allocation limit of 200 bytes"},
+		{"rest([0, 1, 2, 3, 4, 5, 6, 7, 8, 9])", 200, "evaluation exceeded the allocation limit of 200 bytes"},
+		// match式の腕の残りの要素のパターンで上限を超えても、一致しなかったことにしない
+		{"match ([1, 2, 3]) { [x, ...rest] => rest }", 100, "evaluation exceeded the allocation limit of 100 bytes"},
+		// 既存のハッシュのキーへの代入は大きさを増やさない
+		{`let h = {"a": 0}; for (i in range(1000)) { h["a"] = i }; h["a"]`, 100, 999},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOptions(tt.input, Options{MaxAllocBytes: tt.maxAllocBytes})
+		testIntegerOrErrorObject(t, evaluated, tt.expected, ErrAllocLimitExceeded)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"1 && \"a\"", true},
+		{"if (false) { 1 } || 0", true},
+		// 左辺で結果が決まる場合、右辺は評価されない
+		{"false && undefined", false},
+		{"true || undefined", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -134,6 +477,9 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
 	}
 
 	for _, tt := range tests {
@@ -147,6 +493,89 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "other" }`, "two"},
+		{`match (5) { 1 => "one", 2 => "two", _ => "other" }`, "other"},
+		{`match (5) { 1 => "one" }`, nil},
+		{`match (-1) { -1 => "minus one" }`, "minus one"},
+		{`match (2.0) { 2 => "two" }`, "two"},
+		{`match (2) { 2.5 => "float", 2.0 => "two" }`, "two"},
+		{`match ("a") { "a" => 1 }`, 1},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{`let h = match (1) { _ => {"a": 10} }; h["a"]`, 10},
+		{`match (1) { n => { let m = n * 2; m } }`, 2},
+		{`match (1) { "1" => 0, true => 0 }`, nil},
+		// 空のブロックの本体はnullを返す
+		{"match (1) { _ => {} }", nil},
+		{"puts(match (1) { _ => {} })", nil},
+		{"let f = fn() { match (1) { _ => {} } }; f()", nil},
+		{"match (7) { n => n * 2 }", 14},
+		{"match (7) { n if n > 10 => 1, n if n > 5 => 2, _ => 3 }", 2},
+		{"match (3) { n if n > 10 => 1, _ => 3 }", 3},
+		{"match (7) { n => { let m = n + 1; m * 2 } }", 16},
+		{"match ([]) { [] => 0, _ => 1 }", 0},
+		{"match ([1, 2]) { [a] => a, [a, b] => a + b }", 3},
+		{"match ([1, 2, 3]) { [a, b] => 0, [1, _, c] => c }", 3},
+		{"match ([1, 2, 3]) { [first, ...rest] => rest }", []int{2, 3}},
+		{"match ([1]) { [first, ...rest] => rest }", []int{}},
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", 6},
+		{"match (1) { [a] => a }", nil},
+		{`match ({"x": 1, "y": 2}) { {"x": x, "y": y} => x + y }`, 3},
+		{`match ({"x": 1, "y": 2}) { {y} => y }`, 2},
+		{`match ({"x": 1}) { {"y": y} => y, {"x": 0} => 0, {"x": x} => x * 10 }`, 10},
+		{`match ({1: [2]}) { {1: [v]} => v }`, 2},
+		{`match ([1]) { {x} => x }`, nil},
+		// 束縛はその腕の中だけで有効で、外側の変数を上書きしない
+		{"let x = 1; match (2) { x => x }; x", 1},
+		{"let x = 1; match ([2, 3]) { [x, y] if x > 5 => 0, _ => x }", 1},
+		{"let f = fn(n) { match (n) { 0 => { return 100 } _ => n } }; f(0)", 100},
+		{"match (y) { _ => 1 }", "identifier not found: y"},
+		{"match (1) { n if n + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match (1) { _ => y }", "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value for %q. got=%q, want=%q", tt.input, obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -253,6 +682,50 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"true && foobar",
+			"identifier not found: foobar",
+		},
+		{
+			"5 / 0",
+			"division by zero",
+		},
+		{
+			"x = 1",
+			"assignment to undefined variable: x",
+		},
+		{
+			"x += 1",
+			"assignment to undefined variable: x",
+		},
+		{
+			"let f = fn() { y = 1 }; f()",
+			"assignment to undefined variable: y",
+		},
+		{
+			`let s = "a"; s -= 1`,
+			"type mismatch: STRING - INTEGER",
+		},
+		{
+			"5 % 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"1 >> -2",
+			"negative shift count: -2",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
 		{
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
@@ -261,6 +734,14 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"let x = 1 + ; x",
+			"cannot evaluate expression with syntax errors",
+		},
+		{
+			"let = 1; 2",
+			"cannot evaluate statement with syntax errors",
+		},
 	}
 
 	for _, tt := range tests {
@@ -287,6 +768,7 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let 数 = 5; let ñ = 数 * 2; ñ;", 10},
 	}
 
 	for _, tt := range tests {
@@ -294,6 +776,240 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, _, c] = [1, 2, 3]; a + c", 4},
+		{"let [first, ...rest] = [1, 2, 3]; rest", []int{2, 3}},
+		{"let [first, ...rest] = [1]; rest", []int{}},
+		{"let [a, [b, c]] = [1, [2, 3]]; a + b + c", 6},
+		{`let {name, age} = {"name": "monkey", "age": 3}; if (age == 3) { name }`, "monkey"},
+		{`let {"pos": [x, y]} = {"pos": [3, 4]}; x * y`, 12},
+		{`let {1: one} = {1: "one"}; one`, "one"},
+		{"let pair = fn() { [1, 2] }; let [x, y] = pair(); x + y", 3},
+		{"let [a, b] = [1, 2, 3];", "array pattern [a, b] expects 2 elements, got 3"},
+		{"let [a, b, ...rest] = [1];", "array pattern [a, b, ...rest] expects at least 2 elements, got 1"},
+		{"let [a, b] = 1;", "cannot destructure INTEGER with array pattern [a, b]"},
+		{"let {name} = [1];", "cannot destructure ARRAY with hash pattern {name:name}"},
+		{`let {name, age} = {"name": "monkey"};`, "key not found: age"},
+		{"let [a, [b, c]] = [1, [2]];", "array pattern [b, c] expects 2 elements, got 1"},
+		{"let [a, b] = c;", "identifier not found: c"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value for %q. got=%q, want=%q", tt.input, obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = 1; a = 2; a;", 2},
+		{"let a = 1; a = 2;", 2},
+		{"let a = 1; let b = 2; a = b = 3; a + b;", 6},
+		{"let a = 10; a += 5; a;", 15},
+		{"let a = 10; a -= 5; a;", 5},
+		{"let a = 10; a *= 5; a;", 50},
+		{"let a = 10; a /= 5; a;", 2},
+		{"let a = 1.5; a *= 2; a;", 3.0},
+		{`let s = "foo"; s += "bar"; s;`, "foobar"},
+		// クロージャから外側のスコープの変数を書き換える
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c();", 3},
+		{"let total = 0; let add = fn(x) { total += x; }; add(2); add(3); total;", 5},
+		// 関数内のletは外側を書き換えない
+		{"let a = 1; let f = fn() { let a = 2; a = 3; a }; f() + a;", 4},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a;", []int{10, 2, 3}},
+		{"let a = [1, 2, 3]; a[2] += 10; a;", []int{1, 2, 13}},
+		{"let a = [1, 2, 3]; a[1] = 5;", 5},
+		{"let m = [[0, 0], [0, 0]]; m[1][0] = 7; m[1];", []int{7, 0}},
+		// 配列はその場で書き換えるので、同じ配列を参照する変数からも見える
+		{"let a = [1]; let b = a; b[0] = 2; a;", []int{2}},
+		{"let f = fn(xs) { xs[0] = 9 }; let a = [1, 2]; f(a); a;", []int{9, 2}},
+		{`let h = {}; h["k"] = 1; h["k"];`, 1},
+		{`let h = {"k": 1}; h["k"] += 41; h["k"];`, 42},
+		{`let h = {}; h[1] = 1; h[true] = 2; h[1] + h[true];`, 3},
+		{`let h = {"a": 1}; h["a"] = 2; len([h["a"]]);`, 1},
+		{"let a = [1, 2, 3]; a[3] = 4", "index out of range: 3 (length 3)"},
+		{"let a = [1, 2, 3]; a[-1] = 4", "index out of range: -1 (length 3)"},
+		{`let a = [1]; a["0"] = 4`, "array index must be INTEGER, got STRING"},
+		{"let h = {}; h[[1]] = 4", "unusable as hash key: ARRAY"},
+		{`let h = {}; h["x"] += 1`, `key not found: x`},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{"undefined[0] = 1", "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let i = 0; while (false) { i += 1 }; i", 0},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break } }; i", 3},
+		{"let i = 0; let n = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue } n += 1 }; n", 5},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{"let sum = 0; for (i in range(5)) { sum += i }; sum", 10},
+		{"let sum = 0; for (i in range(1, 4)) { sum += i }; sum", 6},
+		{"let xs = []; for (i in range(10, 0, -3)) { xs = push(xs, i) }; xs", []int{10, 7, 4, 1}},
+		{"let xs = []; for (i in range(0)) { xs = push(xs, i) }; xs", []int{}},
+		{`let s = ""; for (c in "abc") { s = c + s }; s`, "cba"},
+		{`let s = ""; for (c in "日本") { s += c + "," }; s`, "日,本,"},
+		{`let s = ""; for (k in {"b": 1, "a": 2, "c": 3}) { s += k }; s`, "abc"},
+		{`let xs = []; for (k in {3: 0, 1: 0, 2: 0}) { xs = push(xs, k) }; xs`, []int{1, 2, 3}},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break } n += x }; n", 3},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { continue } n += x }; n", 7},
+		{"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break } n += 1 } }; n", 3},
+		// ループの中のreturnは関数から抜ける
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } } 0 }; f()", 20},
+		{"let f = fn() { while (true) { return 7 } }; f()", 7},
+		// 最後まで回ったループの値はnull
+		{"let f = fn() { let x = 1; while (x < 3) { x += 1; } }; f() + 1", "type mismatch: NULL + INTEGER"},
+		{"let f = fn() { for (x in [1, 2]) { x } }; f() + 1", "type mismatch: NULL + INTEGER"},
+		{"let f = fn() { while (true) { break } }; f() + 1", "type mismatch: NULL + INTEGER"},
+		// ループ変数は繰り返しごとに束縛されるので、クロージャはその回の値を捕捉する
+		{"let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }) }; fs[0]() + fs[2]()", 2},
+		{"let xs = [1, 2, 3]; for (i in range(len(xs))) { xs[i] *= 2 }; xs", []int{2, 4, 6}},
+		{"len(range(0, 10, 3))", 4},
+		{"for (x in 1) { x }", "cannot iterate over INTEGER"},
+		{"while (x) { 1 }", "identifier not found: x"},
+		{"for (x in [1]) { y }", "identifier not found: y"},
+		{"range(0, 1, 0)", "`range` step must not be zero"},
+		{"range()", "wrong number of arguments to `range`: got=0, want=1 to 3"},
+		{`range("a")`, "arguments to `range` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value for %q. got=%q, want=%q", tt.input, obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -330,6 +1046,8 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"let one = fn() { return 1; }; one() + 1;", 2},
+		{"let f = fn(x) { if (x > 0) { return x; } 0 }; f(3) * 2;", 6},
 	}
 
 	for _, tt := range tests {
@@ -337,6 +1055,161 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// 末尾位置の呼び出しはGoのスタックを消費しないので、深い再帰でもスタックオーバーフローしない
+		{"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(100000, 0)", 5000050000},
+		{"let count = fn(n) { match (n) { 0 => 0, _ => count(n - 1) } }; count(100000)", 0},
+		{"let loop = fn(n) { while (true) { if (n == 0) { return 42 } return loop(n - 1) } }; loop(100000)", 42},
+		{"let f = fn(n) { for (x in [1]) { if (n == 0) { return 0 } return f(n - 1) } }; f(100000)", 0},
+		{"let f = fn(n) { if (n > 0) { return f(n - 1) }; n }; f(100000)", 0},
+		{"let f = fn(n) { match (n) { 0 => { return 0 }, _ => { return f(n - 1) } }; 1 }; f(100000)", 0},
+		{`let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+if (isEven(100001)) { 1 } else { 0 }`, 0},
+		{"let last = fn(xs) { if (len(xs) == 1) { xs[0] } else { last(rest(xs)) } }; last([1, 2, 3])", 3},
+		// 末尾位置の組み込み関数、クロージャの呼び出し
+		{"let f = fn(xs) { len(xs) }; f([1, 2])", 2},
+		{"let adder = fn(x) { fn(y) { x + y } }; let f = fn(n) { adder(n)(1) }; f(1)", 2},
+		// 末尾位置でない呼び出しは従来どおり
+		{"let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(10)", 3628800},
+		{"let f = fn(n) { g(n) }; let g = fn(a, b) { a }; f(1)", "wrong number of arguments to `g`: got=1, want=2"},
+		{"let f = fn() { 1(2) }; f()", "not a function: INTEGER"},
+		{"let f = fn() { len(1) }; f()", "argument to `len` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+// 式の途中にあるreturn文の呼び出しはその場で評価し、末尾呼び出しとして外に出さない
+func TestReturnInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let g = fn() { 42 }; let f = fn() { [if (true) { return g() }] }; f()", "[42]"},
+		{"let g = fn() { 42 }; let f = fn() { let xs = [match (1) { _ => { return g() } }]; xs }; f()", "[42]"},
+		{"let g = fn() { 42 }; let f = fn() { return if (true) { return g() } else { 1 } }; f()", "42"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%T(%+v)", tt.input, tt.expected, evaluated, evaluated)
+		}
+	}
+}
+
+func TestFunctionArity(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		expectedPos string
+	}{
+		{"let add = fn(a, b) { a + b };\nadd(1);", "wrong number of arguments to `add`: got=1, want=2", "2:1"},
+		{"let add = fn(a, b) { a + b };\nadd(1, 2, 3);", "wrong number of arguments to `add`: got=3, want=2", "2:1"},
+		{"fn(x) { x }();", "wrong number of arguments to anonymous function: got=0, want=1", "1:1"},
+		{"let f = fn() { 1 };\nlet g = f;\ng(1);", "wrong number of arguments to `f`: got=1, want=0", "3:1"},
+		{"quote()", "wrong number of arguments to `quote`: got=0, want=1", "1:1"},
+		{"quote(1, 2)", "wrong number of arguments to `quote`: got=2, want=1", "1:1"},
+		{"fn() { quote() }()", "wrong number of arguments to `quote`: got=0, want=1", "1:8"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error pos. expected=%s, got=%s", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestDefaultRestAndSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		// 既定値は呼び出しのたびに評価される
+		{"let f = fn(xs = []) { push(xs, 1) }; f(); f()", []int{1}},
+		{"let f = fn(...rest) { rest }; f(1, 2, 3)", []int{1, 2, 3}},
+		{"let f = fn(...rest) { rest }; f()", []int{}},
+		{"let f = fn(x, ...rest) { rest }; f(1, 2, 3)", []int{2, 3}},
+		{"let f = fn(x, y = 2, ...rest) { [x, y, len(rest)] }; f(1)", []int{1, 2, 0}},
+		{"let add = fn(a, b) { a + b }; let xs = [1, 2]; add(...xs)", 3},
+		{"let f = fn(...rest) { rest }; f(0, ...[1, 2], ...[], 3)", []int{0, 1, 2, 3}},
+		{"let xs = [2, 3]; [1, ...xs, 4]", []int{1, 2, 3, 4}},
+		{"len(...[[1, 2, 3]])", 3},
+		{"let f = fn(x, y = 10) { x + y }; f()", "wrong number of arguments to `f`: got=0, want=1 to 2"},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2, 3)", "wrong number of arguments to `f`: got=3, want=1 to 2"},
+		{"let f = fn(x, ...rest) { x }; f()", "wrong number of arguments to `f`: got=0, want=at least 1"},
+		{"let f = fn(x = y) { x }; f()", "identifier not found: y"},
+		{"let f = fn(...rest) { rest }; f(...1)", "cannot spread INTEGER, expected ARRAY"},
+		{"[...{}]", "cannot spread HASH, expected ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -373,8 +1246,15 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("日本")`, 2},
+		{`len("🐒 monkey")`, 8},
+		{`bytes("日本")`, 6},
+		{`bytes("monkey")`, 6},
+		{`bytes(1)`, "argument to `bytes` must be STRING, got INTEGER"},
+		{`bytes()`, "wrong number of arguments to `bytes`: got=0, want=1"},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len("one", "two")`, "wrong number of arguments to `len`: got=2, want=1"},
+		{`push([])`, "wrong number of arguments to `push`: got=1, want=2"},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
 		{`first([1, 2, 3])`, 1},
@@ -388,6 +1268,15 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
 		{`puts("hello", "world!")`, nil},
+		{`int(3.99)`, 3},
+		{`int(-3.99)`, -3},
+		{`int("42")`, 42},
+		{`int(7)`, 7},
+		{`int("4.2")`, `could not parse "4.2" as integer`},
+		{`int(1.0 / 0)`, "cannot convert +Inf to INTEGER"},
+		{`int(true)`, "argument to `int` not supported, got BOOLEAN"},
+		{`float("abc")`, `could not parse "abc" as float`},
+		{`float([])`, "argument to `float` not supported, got ARRAY"},
 	}
 
 	for _, tt := range tests {
@@ -475,6 +1364,76 @@ func TestArrayIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[][0:]", []int{}},
+		// スライスは新しい配列なので、書き換えても元の配列は変わらない
+		{"let xs = [1, 2, 3]; let ys = xs[0:2]; ys[0] = 9; xs", []int{1, 2, 3}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[:0]`, ""},
+		{`"日本語"[1:]`, "本語"},
+		{`"hello"[1]`, "e"},
+		{`"日本語"[2]`, "語"},
+		{`"日本語"[1:2]`, "本"},
+		{`"日本語"[-2:-1]`, "本"},
+		{`"日本語"[0:10]`, "日本語"},
+		{`"日本語"[3]`, nil},
+		{`"hello"[5]`, nil},
+		{`"hello"[-1]`, nil},
+		{"1[0:1]", "slice operator not supported: INTEGER"},
+		{`[1, 2]["a":]`, "slice index must be INTEGER, got STRING"},
+		{"[1, 2][:x]", "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value for %q. got=%q, want=%q", tt.input, obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -527,6 +1486,8 @@ func TestHashIndexExpressions(t *testing.T) {
 		{`{5: 5}[5]`, 5},
 		{`{true: 5}[true]`, 5},
 		{`{false: 5}[false]`, 5},
+		{`{1: 5}[1.0]`, 5},
+		{`{1.5: 5}[1.5]`, 5},
 	}
 
 	for _, tt := range tests {
@@ -539,3 +1500,38 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"5 + true;", "1:1", "1:9"},
+		{"let x = 1;\nlet y = x + foobar;", "2:13", "2:19"},
+		{"let f = fn(x) { -x };\nf(true);", "1:17", "1:19"},
+		{`len(1, 2)`, "1:1", "1:10"},
+		{"let x = 10;\nx / (x - 10)", "2:1", "2:12"},
+		{"let [a, [b, c]] = [1, [2]];", "1:9", "1:15"},
+		{`let {name, age} = {"name": 1};`, "1:12", "1:15"},
+		{"let f = fn(n) {\n  g(n)\n};\nlet g = fn(a, b) { a };\nf(1)", "2:3", "2:7"},
+		{"let f = fn() {\n  len(1)\n};\nf()", "2:3", "2:9"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error pos for %q. expected=%s, got=%s", tt.input, tt.expectedPos, errObj.Pos)
+		}
+		if errObj.End.String() != tt.expectedEnd {
+			t.Errorf("wrong error end for %q. expected=%s, got=%s", tt.input, tt.expectedEnd, errObj.End)
+		}
+	}
+}
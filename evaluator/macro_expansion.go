@@ -27,7 +27,7 @@ func DefineMacros(program *ast.Program, env *object.Environment) {
 
 func isMacroDefinition(node ast.Statement) bool {
 	letStatement, ok := node.(*ast.LetStatement)
-	if !ok {
+	if !ok || letStatement.Name == nil {
 		return false
 	}
 
@@ -43,6 +43,7 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
 
 	macro := &object.Macro{
+		Name:       letStatement.Name.Value,
 		Parameters: macroLiteral.Parameters,
 		Env:        env,
 		Body:       macroLiteral.Body,
@@ -51,8 +52,23 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 	env.Set(letStatement.Name.Value, macro)
 }
 
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(program, func(node ast.Node) ast.Node {
+// ExpandMacros マクロ呼び出しを既定のオプションで展開したASTを返す
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	return ExpandMacrosWithOptions(program, env, Options{})
+}
+
+// ExpandMacrosWithOptions マクロ呼び出しを展開したASTを返す。マクロの本体は指定のオプションで評価する
+// 評価したノードの数と作った値の大きさは、すべてのマクロ呼び出しの展開を通して数える
+// 展開に失敗した場合は呼び出し位置を持つエラーを返し、以降のマクロ呼び出しは展開しない
+func ExpandMacrosWithOptions(program ast.Node, env *object.Environment, opts Options) (ast.Node, *object.Error) {
+	e := newEvaluator(opts)
+	var expandErr *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if expandErr != nil {
+			return node
+		}
+
 		callExpression, ok := node.(*ast.CallExpression)
 		if !ok {
 			return node
@@ -63,18 +79,49 @@ func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
 			return node
 		}
 
-		args := quoteArgs(callExpression)
-		evalEnv := extendMacroEnv(macro, args)
-
-		evaluated := Eval(macro.Body, evalEnv)
-
-		quote, ok := evaluated.(*object.Quote)
-		if !ok {
-			panic("we only support returning AST-nodes from macros")
+		quote, err := e.expandMacro(macro, callExpression)
+		if err != nil {
+			if !err.Pos.IsValid() {
+				err.Pos, err.End = callExpression.Pos(), callExpression.End()
+			}
+			expandErr = err
+			return node
 		}
 
 		return quote.Node
 	})
+
+	return expanded, expandErr
+}
+
+// マクロ呼び出しを評価し、マクロが返したQuoteを返す
+func (e *evaluator) expandMacro(macro *object.Macro, call *ast.CallExpression) (*object.Quote, *object.Error) {
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			return nil, newError("cannot spread arguments to macro `%s`", macro.Name)
+		}
+	}
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, wrongNumberOfArguments(macro.Name, len(call.Arguments), len(macro.Parameters))
+	}
+
+	args := quoteArgs(call)
+	evalEnv := extendMacroEnv(macro, args)
+
+	evaluated := e.eval(macro.Body, evalEnv)
+	if err, ok := evaluated.(*object.Error); ok {
+		return nil, err
+	}
+	if evaluated == nil {
+		evaluated = NULL
+	}
+
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		return nil, newError("macro `%s` must return a quoted AST node, got %s", macro.Name, evaluated.Type())
+	}
+
+	return quote, nil
 }
 
 func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
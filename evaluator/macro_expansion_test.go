@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/ktny/monkey/ast"
@@ -101,10 +103,125 @@ func TestExpandMacros(t *testing.T) {
 
 		env := object.NewEnvironment()
 		DefineMacros(program, env)
-		expanded := ExpandMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros returned error: %s", err.Message)
+		}
 
 		if expanded.String() != expected.String() {
 			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
 		}
 	}
 }
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		expectedPos string
+	}{
+		{
+			`let twice = macro(x) { quote(unquote(x) * 2); };
+twice(1, 2);`,
+			"wrong number of arguments to `twice`: got=2, want=1",
+			"2:1",
+		},
+		{
+			`let twice = macro(x) { quote(unquote(x) * 2); };
+twice();`,
+			"wrong number of arguments to `twice`: got=0, want=1",
+			"2:1",
+		},
+		{
+			`let twice = macro(x) { quote(unquote(x) * 2); };
+twice(...[1]);`,
+			"cannot spread arguments to macro `twice`",
+			"2:1",
+		},
+		{
+			`let notQuote = macro() { 1 };
+notQuote();`,
+			"macro `notQuote` must return a quoted AST node, got INTEGER",
+			"2:1",
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("ExpandMacros returned no error for %q", tt.input)
+			continue
+		}
+
+		if err.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Message)
+		}
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error pos. expected=%s, got=%s", tt.expectedPos, err.Pos)
+		}
+	}
+}
+
+func TestExpandMacrosWithOptions(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input         string
+		opts          Options
+		expected      string
+		expectedCause error
+	}{
+		{
+			`let loop = macro() { while (true) { } };
+loop();`,
+			Options{MaxSteps: 1000},
+			"evaluation exceeded the step limit of 1000",
+			ErrStepLimitExceeded,
+		},
+		{
+			`let loop = macro() { while (true) { } };
+loop();`,
+			Options{Context: cancelled},
+			"evaluation cancelled",
+			context.Canceled,
+		},
+		{
+			`let grow = macro() { let s = "a"; while (true) { s = s + s } };
+grow();`,
+			Options{MaxAllocBytes: 10000},
+			"evaluation exceeded the allocation limit of 10000 bytes",
+			ErrAllocLimitExceeded,
+		},
+		{
+			`let deep = macro() { let f = fn(n) { 1 + f(n) }; f(0) };
+deep();`,
+			Options{MaxCallDepth: 10},
+			"maximum call depth exceeded",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacrosWithOptions(program, env, tt.opts)
+		if err == nil {
+			t.Errorf("ExpandMacrosWithOptions returned no error for %q", tt.input)
+			continue
+		}
+
+		if err.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Message)
+		}
+		if !errors.Is(err.Cause, tt.expectedCause) {
+			t.Errorf("wrong error cause. expected=%v, got=%v", tt.expectedCause, err.Cause)
+		}
+	}
+}
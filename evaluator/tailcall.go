@@ -0,0 +1,110 @@
+package evaluator
+
+import (
+	"github.com/ktny/monkey/ast"
+	"github.com/ktny/monkey/object"
+)
+
+// tailCall 末尾位置にある関数呼び出し。呼び出し先と評価済みの引数を持ち、applyFunctionのループで呼び出す
+// evalTailがたどる末尾位置からapplyFunctionに戻るまでの間だけ使い、評価結果として外に出ることはない
+type tailCall struct {
+	call *ast.CallExpression
+	fn   object.Object
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return tc.call.String() }
+
+// ノードを関数の本体の末尾位置として評価する。ノードの値がそのまま関数の結果になる
+// ブロックの最後の文、if式とmatch式の分岐の中をたどり、末尾位置にある関数呼び出しは呼び出さずに*tailCallを返す
+// return文はその値を末尾位置として評価する。式の途中などにあるreturn文はe.evalがその場で評価するので、*tailCallは式の値にならない
+func (e *evaluator) evalTail(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		return e.evalTailBlockStatement(node, env)
+	case *ast.ExpressionStatement:
+		return e.evalTail(node.Expression, env)
+	case *ast.ReturnStatement:
+		return wrapReturnValue(e.evalTail(node.ReturnValue, env))
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env, e.evalReturnTail)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env, e.evalReturnTail)
+	case *ast.IfExpression:
+		return e.evalIfExpression(node, env, e.evalTail)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env, e.evalTail)
+	case *ast.CallExpression:
+		// quoteは引数を評価しない特別な形なので、引数の数の検査も含めて通常の評価に任せる
+		if node.Function.TokenLiteral() == "quote" {
+			return e.eval(node, env)
+		}
+		function := e.eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return &tailCall{call: node, fn: function, args: args}
+	default:
+		return e.eval(node, env)
+	}
+}
+
+// ブロック文を末尾位置として評価する。最後の文だけが末尾位置になり、それ以前の文はreturn文だけが末尾位置になる
+func (e *evaluator) evalTailBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		if i == len(block.Statements)-1 {
+			return e.evalTail(statement, env)
+		}
+
+		result = e.evalReturnTail(statement, env)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// 値は捨てられるが、中のreturn文の値は関数の結果に直接届く文を評価する。途中の文やループの本体がこれにあたる
+// ブロック、if式とmatch式の分岐、ループの本体の中をたどり、return文の値だけを末尾位置として評価する
+func (e *evaluator) evalReturnTail(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		var result object.Object
+		for _, statement := range node.Statements {
+			result = e.evalReturnTail(statement, env)
+
+			if result != nil {
+				rt := result.Type()
+				if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
+					return result
+				}
+			}
+		}
+		return result
+	case *ast.ExpressionStatement:
+		return e.evalReturnTail(node.Expression, env)
+	case *ast.ReturnStatement:
+		return wrapReturnValue(e.evalTail(node.ReturnValue, env))
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env, e.evalReturnTail)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env, e.evalReturnTail)
+	case *ast.IfExpression:
+		return e.evalIfExpression(node, env, e.evalReturnTail)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env, e.evalReturnTail)
+	default:
+		return e.eval(node, env)
+	}
+}
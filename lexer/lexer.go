@@ -1,44 +1,100 @@
 package lexer
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/ktny/monkey/token"
 )
 
-// Lexer 字句解析
+// Lexer 字句解析。入力は先読みの1文字を除いて必要になるまで読まない
 type Lexer struct {
-	input        string
-	position     int // 現在位置
-	readPosition int // 次の位置
-	ch           byte
+	r        io.RuneReader
+	err      error // 入力の読み込みで起きたエラー。読み終えればio.EOF
+	filename string
+	position int  // 現在位置のバイトオフセット
+	ch       rune // 現在位置の文字。入力の終わりでは0
+	size     int  // 現在位置の文字のUTF-8でのバイト数
+	peek     rune // 次の位置の文字
+	peekSize int  // 次の位置の文字のUTF-8でのバイト数
+	line     int  // 現在位置の行番号
+	column   int  // 現在位置の列番号。文字（コードポイント）単位で数える
 }
 
 // New Lexerインスタンスを返す
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile ファイル名付きのLexerインスタンスを返す。ファイル名はトークンの位置情報に使われる
+func NewFile(filename, input string) *Lexer {
+	return NewReader(filename, strings.NewReader(input))
+}
+
+// NewReader rから読みながら字句解析するLexerインスタンスを返す
+// rがio.RuneReaderでなければbufio.Readerで包む。ファイル名はトークンの位置情報に使われる
+func NewReader(filename string, r io.Reader) *Lexer {
+	rr, ok := r.(io.RuneReader)
+	if !ok {
+		rr = bufio.NewReader(r)
+	}
+
+	l := &Lexer{r: rr, filename: filename, line: 1}
+	l.peek, l.peekSize = l.readRune()
 	l.readChar()
 	return l
 }
 
+// Err 入力の読み込みで起きたエラーを返す。入力を最後まで読めていればnilを返す
+// 読み込みに失敗した場合、Lexerはそこで入力が終わったものとしてEOFトークンを返す
+func (l *Lexer) Err() error {
+	if l.err == io.EOF {
+		return nil
+	}
+	return l.err
+}
+
 // NextToken 現在のl.chに対応するTokenを返し次に進む
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
 
+	pos := l.pos()
+
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
+		switch l.peekChar() {
+		case '=':
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.EQ, Literal: literal}
-		} else {
+		case '>':
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
+		default:
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+="}
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.MINUS_ASSIGN, Literal: "-="}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -49,13 +105,77 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			tok.Type = token.COMMENT
+			tok.Literal = l.readLineComment()
+			tok.Pos, tok.End = pos, l.pos()
+			return tok
+		case '*':
+			literal, ok := l.readBlockComment()
+			if !ok {
+				tok.Type = token.ILLEGAL
+				tok.Literal = "unterminated block comment"
+				tok.Pos, tok.End = pos, l.pos()
+				return tok
+			}
+			tok.Type = token.COMMENT
+			tok.Literal = literal
+		case '=':
+			l.readChar()
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/="}
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*="}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		case '<':
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_LEFT, Literal: "<<"}
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		case '>':
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_RIGHT, Literal: ">>"}
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -74,91 +194,347 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '.' {
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			} else {
+				tok.Type = token.ILLEGAL
+				tok.Literal = `unexpected characters ".."`
+			}
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = fmt.Sprintf("unexpected character %q", l.ch)
+		}
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = newStringToken(l.readString())
+		// 閉じられないまま行が終わった文字列は改行を含めない
+		if l.ch == '\n' {
+			tok.Pos, tok.End = pos, l.pos()
+			return tok
+		}
+	case '`':
+		tok = newStringToken(l.readRawString())
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		tok.Pos, tok.End = pos, pos
+		return tok
 	// 1, 2文字トークン以外は識別子または数値として字句解析する
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok = l.readNumber()
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
+		} else if l.invalidUTF8() {
+			tok.Type = token.ILLEGAL
+			tok.Literal = "invalid UTF-8 encoding"
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok.Type = token.ILLEGAL
+			tok.Literal = fmt.Sprintf("unexpected character %q", l.ch)
 		}
 	}
 
 	l.readChar()
+	tok.Pos, tok.End = pos, l.pos()
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
 // 現在の文字から連続する文字列を返す
 func (l *Lexer) readIdentifier() string {
-	position := l.position
+	var out strings.Builder
 	for isLetter(l.ch) {
+		l.take(&out)
+	}
+	return out.String()
+}
+
+// 現在の文字から連続する数値のトークンを返す
+// 小数部(.5)か指数部(e-9)を含めば浮動小数点数、含まなければ整数になる
+// 0x, 0o, 0b で始まる整数は英数字と _ をまとめて読み、桁の検証は構文解析器に任せる
+func (l *Lexer) readNumber() token.Token {
+	var out strings.Builder
+	tokenType := token.TokenType(token.INT)
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.take(&out)
+		l.take(&out)
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.take(&out)
+		}
+		return token.Token{Type: tokenType, Literal: out.String()}
+	}
+
+	l.readDigits(&out)
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.take(&out)
+		l.readDigits(&out)
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.take(&out)
+		if l.ch == '+' || l.ch == '-' {
+			l.take(&out)
+		}
+		if !isDigit(l.ch) {
+			return token.Token{Type: token.ILLEGAL, Literal: "malformed exponent in number literal"}
+		}
+		l.readDigits(&out)
+	}
+
+	return token.Token{Type: tokenType, Literal: out.String()}
+}
+
+// 現在の文字から連続する数字と桁区切りの _ をoutに書き出しながら読み進める
+func (l *Lexer) readDigits(out *strings.Builder) {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.take(out)
+	}
+}
+
+// 行末までの // コメントを返す。改行は含まない
+func (l *Lexer) readLineComment() string {
+	var out strings.Builder
+	for l.ch != '\n' && l.ch != 0 {
+		l.take(&out)
+	}
+	return out.String()
+}
+
+// /* から */ までのコメントを返す。現在の文字は閉じの / になる
+// 閉じられないまま入力が終わった場合はfalseを返す
+func (l *Lexer) readBlockComment() (string, bool) {
+	var out strings.Builder
+	l.take(&out)
+	out.WriteRune(l.ch)
+	for {
 		l.readChar()
+		if l.ch == 0 {
+			return "", false
+		}
+		out.WriteRune(l.ch)
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			out.WriteRune(l.ch)
+			return out.String(), true
+		}
+	}
+}
+
+// 文字列リテラルのトークンを返す。リテラルが不正であればエラーの内容を持つILLEGALトークンを返す
+func newStringToken(value string, err error) token.Token {
+	if err != nil {
+		return token.Token{Type: token.ILLEGAL, Literal: err.Error()}
 	}
-	return l.input[position:l.position]
+	return token.Token{Type: token.STRING, Literal: value}
 }
 
-// 現在の文字から連続する数値を文字列として返す
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+// " で囲まれた文字列を読み、エスケープシーケンスを解釈した値を返す。現在の文字は閉じの " になる
+// 文字列は改行を含められず、閉じられないまま行が終わればエラーを返す
+func (l *Lexer) readString() (string, error) {
+	var out strings.Builder
+	var err error
+
+	for {
 		l.readChar()
+		switch l.ch {
+		case '"':
+			return out.String(), err
+		case 0, '\n':
+			return "", errors.New("unterminated string literal")
+		case '\\':
+			// 行末の \ は閉じられていない文字列として次の周回で扱う
+			if l.peekChar() == 0 || l.peekChar() == '\n' {
+				continue
+			}
+			l.readChar()
+			ch, escErr := l.readEscape()
+			if escErr != nil && err == nil {
+				err = escErr
+			}
+			out.WriteRune(ch)
+		default:
+			if l.invalidUTF8() && err == nil {
+				err = errors.New("invalid UTF-8 encoding in string literal")
+			}
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// \ に続くエスケープシーケンスを解釈して文字を返す。現在の文字はシーケンスの末尾になる
+func (l *Lexer) readEscape() (rune, error) {
+	switch l.ch {
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '\\':
+		return '\\', nil
+	case '"':
+		return '"', nil
+	case 'u':
+		return l.readUnicodeEscape()
+	default:
+		return utf8.RuneError, fmt.Errorf("unknown escape sequence: \\%c", l.ch)
 	}
-	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// \u{...} の16進数で表されたコードポイントを返す
+func (l *Lexer) readUnicodeEscape() (rune, error) {
+	invalid := errors.New("invalid unicode escape: expected \\u{} with 1 to 6 hex digits")
+
+	if l.peekChar() != '{' {
+		return utf8.RuneError, invalid
+	}
+	l.readChar()
+
+	var value rune
+	digits := 0
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+		if digits < 6 {
+			value = value*16 + hexValue(l.ch)
+		}
+		digits++
+	}
+
+	if l.peekChar() != '}' {
+		return utf8.RuneError, invalid
+	}
+	l.readChar()
+
+	if digits == 0 || digits > 6 {
+		return utf8.RuneError, invalid
+	}
+	if !utf8.ValidRune(value) {
+		return utf8.RuneError, fmt.Errorf("invalid unicode code point: U+%X", value)
+	}
+	return value, nil
+}
+
+// ` で囲まれた生文字列を読む。エスケープシーケンスは解釈せず、改行も含められる
+func (l *Lexer) readRawString() (string, error) {
+	var out strings.Builder
+	var err error
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case '`':
+			return out.String(), err
+		case 0:
+			return "", errors.New("unterminated raw string literal")
+		default:
+			if l.invalidUTF8() && err == nil {
+				err = errors.New("invalid UTF-8 encoding in string literal")
+			}
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
 }
 
-// 次の位置の文字を読み、readPositionを進める
+// 次の位置に進み、入力からさらに1文字を先読みする
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.column++
 	}
-	l.position = l.readPosition
-	l.readPosition++
+
+	l.position += l.size
+	l.ch, l.size = l.peek, l.peekSize
+	l.peek, l.peekSize = l.readRune()
 }
 
-// 次の位置の文字を読み、readPositionは進めない
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
+// 現在の文字をoutに書き出して次の位置に進む
+func (l *Lexer) take(out *strings.Builder) {
+	out.WriteRune(l.ch)
+	l.readChar()
+}
+
+// 入力からUTF-8として1文字読む。入力の終わりか読み込みに失敗した後は0を返す
+// 不正なバイト列はutf8.RuneErrorの1バイトの文字として読む
+func (l *Lexer) readRune() (rune, int) {
+	if l.err != nil {
+		return 0, 0
+	}
+
+	ch, size, err := l.r.ReadRune()
+	if err != nil {
+		l.err = err
+		return 0, 0
 	}
-	return l.input[l.readPosition]
+	return ch, size
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// 次の位置の文字を返す。位置は進めない
+func (l *Lexer) peekChar() rune {
+	return l.peek
 }
 
-func isDigit(ch byte) bool {
+// 現在の文字がUTF-8として不正なバイトか否かを返す
+func (l *Lexer) invalidUTF8() bool {
+	return l.ch == utf8.RuneError && l.size == 1
+}
+
+// 現在位置を返す
+func (l *Lexer) pos() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.position, Line: l.line, Column: l.column}
+}
+
+// 識別子に使える文字か否かを返す。Unicodeの文字と _ を受け付ける
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// 整数リテラルの基数を表す接頭辞（0x, 0o, 0b の2文字目）か否かを返す
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// 16進数の1桁の値を返す
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
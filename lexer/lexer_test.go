@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/ktny/monkey/token"
 )
@@ -15,7 +18,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -31,6 +34,12 @@ if (5 < 10) {
 [1, 2];
 {"foo": "bar"}
 macro(x, y) { x + y; };
+a && b || c;
+a <= b >= c % d;
+~a & b | c ^ d << 1 >> 2;
+a = 1; a += 1; a -= 1; a *= 2; a /= 2;
+while for in break continue
+match (x) { _ => 1 }
 `
 
 	tests := []struct {
@@ -137,6 +146,67 @@ macro(x, y) { x + y; };
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+		{token.TILDE, "~"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.IDENT, "d"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.INT, "1"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 
@@ -154,3 +224,304 @@ macro(x, y) { x + y; };
 		}
 	}
 }
+
+func TestNextTokenPosition(t *testing.T) {
+	input := "let x = 5;\n  x + \"ab\";"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "test.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "test.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "test.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "test.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "test.mk", Offset: 14, Line: 2, Column: 4}},
+		{token.PLUS, token.Position{Filename: "test.mk", Offset: 15, Line: 2, Column: 5}, token.Position{Filename: "test.mk", Offset: 16, Line: 2, Column: 6}},
+		{token.STRING, token.Position{Filename: "test.mk", Offset: 17, Line: 2, Column: 7}, token.Position{Filename: "test.mk", Offset: 21, Line: 2, Column: 11}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 21, Line: 2, Column: 11}, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}},
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%+v, got=%+v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let 名前 = \"日本語\"; ñ_1 + é\n`ü` \"\xff\" \xfe ★"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+		expectedEnd     string
+	}{
+		{token.LET, "let", "1:1", "1:4"},
+		{token.IDENT, "名前", "1:5", "1:7"},
+		{token.ASSIGN, "=", "1:8", "1:9"},
+		{token.STRING, "日本語", "1:10", "1:15"},
+		{token.SEMICOLON, ";", "1:15", "1:16"},
+		{token.IDENT, "ñ_", "1:17", "1:19"},
+		{token.INT, "1", "1:19", "1:20"},
+		{token.PLUS, "+", "1:21", "1:22"},
+		{token.IDENT, "é", "1:23", "1:24"},
+		{token.STRING, "ü", "2:1", "2:4"},
+		{token.ILLEGAL, "invalid UTF-8 encoding in string literal", "2:5", "2:8"},
+		{token.ILLEGAL, "invalid UTF-8 encoding", "2:9", "2:10"},
+		{token.ILLEGAL, "unexpected character '★'", "2:11", "2:12"},
+		{token.EOF, "", "2:12", "2:12"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.String() != tt.expectedPos || tok.End.String() != tt.expectedEnd {
+			t.Fatalf("tests[%d] - position wrong. expected=%s-%s, got=%s-%s", i, tt.expectedPos, tt.expectedEnd, tok.Pos, tok.End)
+		}
+	}
+
+	// オフセットはバイト単位のまま
+	l = New("日本 x")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Pos.Offset != 7 || tok.Pos.Column != 4 {
+		t.Errorf("position of x wrong. expected offset=7 column=4, got offset=%d column=%d", tok.Pos.Offset, tok.Pos.Column)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	input := "let 名前 = `a\nb`; /* c */ x // d\n42"
+
+	// 1バイトずつしか返さないReaderでも、文字列から字句解析した結果と同じトークンになる
+	expected := New(input)
+	l := NewReader("test.mk", iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		tok := l.NextToken()
+
+		want.Pos.Filename, want.End.Filename = "test.mk", "test.mk"
+		if tok != want {
+			t.Fatalf("tests[%d] - token wrong. expected=%+v, got=%+v", i, want, tok)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	if err := l.Err(); err != nil {
+		t.Errorf("l.Err() returned error: %s", err)
+	}
+}
+
+// 途中まで読んだ後に読み込みに失敗するReader
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestNewReaderError(t *testing.T) {
+	// 読み込みに失敗したらそこで入力が終わったものとして扱い、Errで失敗を報告する
+	readErr := errors.New("read failed")
+	l := NewReader("", &failingReader{data: "x y", err: readErr})
+
+	expected := []token.TokenType{token.IDENT, token.IDENT, token.EOF, token.EOF}
+	for i, tt := range expected {
+		if tok := l.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+
+	if l.Err() != readErr {
+		t.Errorf("l.Err() wrong. expected=%v, got=%v", readErr, l.Err())
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// line comment
+let x = 10 / 2; // trailing
+/* block
+   comment */ x
+/* unterminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// line comment"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block\n   comment */"},
+		{token.IDENT, "x"},
+		{token.ILLEGAL, "unterminated block comment"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"foo bar"`, token.STRING, "foo bar"},
+		{`"a\"b"`, token.STRING, `a"b`},
+		{`"line\n\ttab\\"`, token.STRING, "line\n\ttab\\"},
+		{`"\r"`, token.STRING, "\r"},
+		{`"\u{48}\u{65}\u{1F600}"`, token.STRING, "He\U0001F600"},
+		{`"日本語"`, token.STRING, "日本語"},
+		{"`raw\\n\n\"string\"`", token.STRING, "raw\\n\n\"string\""},
+		{`"unterminated`, token.ILLEGAL, "unterminated string literal"},
+		{"\"broken\nline\"", token.ILLEGAL, "unterminated string literal"},
+		{`"ends with \`, token.ILLEGAL, "unterminated string literal"},
+		{"`unterminated", token.ILLEGAL, "unterminated raw string literal"},
+		{`"\q"`, token.ILLEGAL, `unknown escape sequence: \q`},
+		{`"\u41"`, token.ILLEGAL, `invalid unicode escape: expected \u{} with 1 to 6 hex digits`},
+		{`"\u{}"`, token.ILLEGAL, `invalid unicode escape: expected \u{} with 1 to 6 hex digits`},
+		{`"\u{1234567}"`, token.ILLEGAL, `invalid unicode escape: expected \u{} with 1 to 6 hex digits`},
+		{`"\u{D800}"`, token.ILLEGAL, "invalid unicode code point: U+D800"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("%s - tokentype wrong. expected=%q, got=%q", tt.input, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%s - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiteralResync(t *testing.T) {
+	// 不正なエスケープがあっても閉じの " まで読み進め、続くトークンは正しく字句解析する
+	l := New(`"a\qb" + 1`)
+
+	expected := []token.TokenType{token.ILLEGAL, token.PLUS, token.INT, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	l := New("f(...xs) ..")
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.ILLEGAL, `unexpected characters ".."`},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedTokens   []token.TokenType
+		expectedLiterals []string
+	}{
+		{"3.14", []token.TokenType{token.FLOAT}, []string{"3.14"}},
+		{"1e-9", []token.TokenType{token.FLOAT}, []string{"1e-9"}},
+		{"2.5E+3", []token.TokenType{token.FLOAT}, []string{"2.5E+3"}},
+		{"10e3", []token.TokenType{token.FLOAT}, []string{"10e3"}},
+		{"1.foo", []token.TokenType{token.INT, token.ILLEGAL, token.IDENT}, []string{"1", "unexpected character '.'", "foo"}},
+		{"1e", []token.TokenType{token.ILLEGAL}, []string{"malformed exponent in number literal"}},
+		{"0xFF_ff", []token.TokenType{token.INT}, []string{"0xFF_ff"}},
+		{"0b102", []token.TokenType{token.INT}, []string{"0b102"}},
+		{"0o17+1", []token.TokenType{token.INT, token.PLUS, token.INT}, []string{"0o17", "+", "1"}},
+		{"1_000_000", []token.TokenType{token.INT}, []string{"1_000_000"}},
+		{"1_000.5", []token.TokenType{token.FLOAT}, []string{"1_000.5"}},
+		{"1e+x", []token.TokenType{token.ILLEGAL, token.IDENT}, []string{"malformed exponent in number literal", "x"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expectedType := range tt.expectedTokens {
+			tok := l.NextToken()
+			if tok.Type != expectedType {
+				t.Errorf("%s[%d] - tokentype wrong. expected=%q, got=%q", tt.input, i, expectedType, tok.Type)
+			}
+			if tok.Literal != tt.expectedLiterals[i] {
+				t.Errorf("%s[%d] - literal wrong. expected=%q, got=%q", tt.input, i, tt.expectedLiterals[i], tok.Literal)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("%s - expected EOF, got=%q", tt.input, tok.Type)
+		}
+	}
+}
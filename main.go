@@ -1,19 +1,107 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 
+	"github.com/ktny/monkey/diagnostic"
+	"github.com/ktny/monkey/evaluator"
+	"github.com/ktny/monkey/lexer"
+	"github.com/ktny/monkey/object"
+	"github.com/ktny/monkey/parser"
 	"github.com/ktny/monkey/repl"
 )
 
 func main() {
+	jsonOutput := flag.Bool("json", false, "print diagnostics as JSON")
+	checked := flag.Bool("checked", false, "report integer overflow as an error")
+	maxCallDepth := flag.Int("max-call-depth", 0, "maximum depth of nested function calls (0 for the default, negative for no limit)")
+	maxSteps := flag.Int64("max-steps", 0, "maximum number of nodes to evaluate (0 for no limit)")
+	maxAllocBytes := flag.Int64("max-alloc-bytes", 0, "maximum total size in bytes of arrays, strings and hashes to create (0 for no limit)")
+	timeout := flag.Duration("timeout", 0, "abort running a file after this duration (0 for no limit)")
+	flag.Parse()
+
+	opts := evaluator.Options{CheckedArithmetic: *checked, MaxCallDepth: *maxCallDepth, MaxSteps: *maxSteps, MaxAllocBytes: *maxAllocBytes}
+
+	// ファイルが指定されていればREPLを起動せずに実行する
+	if flag.NArg() > 0 {
+		cancel := func() {}
+		if *timeout > 0 {
+			opts.Context, cancel = context.WithTimeout(context.Background(), *timeout)
+		}
+		code := run(flag.Arg(0), opts, *jsonOutput)
+		cancel()
+		os.Exit(code)
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, opts)
+}
+
+// run ファイルを実行し、終了コードを返す。ファイル名が - であれば標準入力から読む
+// ソースは全体を読み込まずに字句解析しながら読み進め、診断を表示するときだけファイルを読み直す
+func run(filename string, opts evaluator.Options, jsonOutput bool) int {
+	in := os.Stdin
+	if filename == "-" {
+		filename = "<stdin>"
+	} else {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	l := lexer.NewReader(filename, in)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(p.Diagnostics()) != 0 {
+		report(filename, p.Diagnostics(), jsonOutput)
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, macroErr := evaluator.ExpandMacrosWithOptions(program, macroEnv, opts)
+	if macroErr != nil {
+		report(filename, []diagnostic.Diagnostic{macroErr.Diagnostic()}, jsonOutput)
+		return 1
+	}
+
+	if err, ok := evaluator.EvalWithOptions(expanded, env, opts).(*object.Error); ok {
+		report(filename, []diagnostic.Diagnostic{err.Diagnostic()}, jsonOutput)
+		return 1
+	}
+
+	return 0
+}
+
+// 診断を出力する。JSONは標準出力に、テキストは標準エラー出力に書き出す
+// テキストで示すソース行はfilenameを読み直して得る。標準入力のように読み直せなければソース行は表示しない
+func report(filename string, diags []diagnostic.Diagnostic, jsonOutput bool) {
+	if jsonOutput {
+		diagnostic.RenderJSON(os.Stdout, diags)
+		return
+	}
+
+	src, _ := ioutil.ReadFile(filename)
+	diagnostic.Render(os.Stderr, string(src), diags)
 }
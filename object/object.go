@@ -4,18 +4,27 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ktny/monkey/ast"
+	"github.com/ktny/monkey/diagnostic"
+	"github.com/ktny/monkey/token"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	RANGE_OBJ        = "RANGE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
@@ -39,6 +48,27 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// Float 浮動小数点数
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	var s string
+	if abs := math.Abs(f.Value); abs == 0 || 1e-6 <= abs && abs < 1e21 {
+		s = strconv.FormatFloat(f.Value, 'f', -1, 64)
+	} else {
+		s = strconv.FormatFloat(f.Value, 'g', -1, 64)
+	}
+
+	// 整数と区別できるように小数点を付ける。ex. 3.0
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 // Boolean 真偽値
 type Boolean struct {
 	Value bool
@@ -60,15 +90,126 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break break文の評価結果。ReturnValueと同様にループまでブロックを抜ける
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+// Continue continue文の評価結果。ReturnValueと同様にループまでブロックを抜ける
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Range StartからStepずつ増やしてStopの手前までの整数の並び。要素は必要になるまで作らない
+type Range struct {
+	Start int64
+	Stop  int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// Len 並びの要素数を返す
+func (r *Range) Len() int64 {
+	switch {
+	case r.Step > 0 && r.Start < r.Stop:
+		return int64((uint64(r.Stop)-uint64(r.Start)-1)/uint64(r.Step) + 1)
+	case r.Step < 0 && r.Start > r.Stop:
+		return int64((uint64(r.Start)-uint64(r.Stop)-1)/(-uint64(r.Step)) + 1)
+	default:
+		return 0
+	}
+}
+
+// Error 実行時エラー。Pos, Endはエラーが発生したノードの範囲
 type Error struct {
 	Message string
+	Pos     token.Position
+	End     token.Position
+	Stack   []StackFrame // エラーが抜け出してきた関数呼び出しの履歴。最も内側の呼び出しが先頭。関数の外で発生したエラーならnil
+	Cause   error        // 評価の打ち切りなど、プログラムの外の原因を表すGoのエラー。なければnil
+}
+
+// StackFrame 関数呼び出しの履歴の1つの呼び出し
+type StackFrame struct {
+	Function string         // 呼び出した関数の名前。無名関数なら空
+	Pos      token.Position // 呼び出し式の位置
+}
+
+func (f StackFrame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "anonymous function"
+	}
+	return fmt.Sprintf("%s called at %s", name, f.Pos)
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// Diagnostic エラーを構造化された診断に変換する。呼び出し履歴はトレースバックとして補足説明に並べる
+func (e *Error) Diagnostic() diagnostic.Diagnostic {
+	return diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Message:  e.Message,
+		Pos:      e.Pos,
+		End:      e.End,
+		Notes:    e.Traceback(),
+	}
+}
+
+// トレースバックで内側と外側にそれぞれ残す行数
+const tracebackEdgeLines = 10
+
+// Traceback 呼び出し履歴を最も内側の呼び出しから1行ずつ返す。履歴がなければnil
+// 再帰で同じ呼び出しが続く場合は1行にまとめ、繰り返した回数を添える
+// まとめても長い場合は内側と外側のtracebackEdgeLines行ずつを残し、間を省いた呼び出しの数の1行に置き換える
+func (e *Error) Traceback() []string {
+	var lines []string
+	var counts []int // 各行にまとめた呼び出しの数
+	for i := 0; i < len(e.Stack); {
+		j := i + 1
+		for j < len(e.Stack) && e.Stack[j] == e.Stack[i] {
+			j++
+		}
+
+		line := "in " + e.Stack[i].String()
+		if j-i > 1 {
+			line += fmt.Sprintf(" (%d times)", j-i)
+		}
+		lines = append(lines, line)
+		counts = append(counts, j-i)
+		i = j
+	}
+
+	if len(lines) <= 2*tracebackEdgeLines {
+		return lines
+	}
+
+	omitted := 0
+	for _, n := range counts[tracebackEdgeLines : len(lines)-tracebackEdgeLines] {
+		omitted += n
+	}
+	truncated := append([]string{}, lines[:tracebackEdgeLines]...)
+	truncated = append(truncated, fmt.Sprintf("... %d more frames", omitted))
+	return append(truncated, lines[len(lines)-tracebackEdgeLines:]...)
+}
 
 type Function struct {
+	Name       string // 無名関数なら空
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // 呼び出しのたびに評価する既定値。ast.FunctionLiteral.Defaultsと同じ形
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -77,14 +218,9 @@ func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParametersString(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -147,6 +283,14 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// HashKey 整数と等しい値は同じ整数のハッシュキーになる。ex. {1: "a"}[1.0]
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && math.Abs(f.Value) < 1<<63 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -167,7 +311,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.SortedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -177,6 +321,58 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// SortedPairs キーの順に並べたペアを返す
+// キーは真偽値、数値、文字列の順に並び、同じ種類の中では値の小さい順に並ぶ
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+// ハッシュのキーaがbより前に並ぶか否かを返す
+func keyLess(a, b Object) bool {
+	rankA, rankB := keyRank(a), keyRank(b)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+
+	switch a := a.(type) {
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Integer:
+		if b, ok := b.(*Integer); ok {
+			return a.Value < b.Value
+		}
+	}
+	return numberValue(a) < numberValue(b)
+}
+
+// キーの種類の並び順を返す
+func keyRank(key Object) int {
+	switch key.(type) {
+	case *Boolean:
+		return 0
+	case *Integer, *Float:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func numberValue(obj Object) float64 {
+	if i, ok := obj.(*Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*Float).Value
+}
+
 type Hashable interface {
 	HashKey() HashKey
 }
@@ -191,6 +387,7 @@ func (q *Quote) Inspect() string {
 }
 
 type Macro struct {
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ktny/monkey/token"
+)
 
 func TestStringKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -18,3 +22,170 @@ func TestStringKey(t *testing.T) {
 		t.Errorf("strings with different content have different hash keys")
 	}
 }
+
+func TestFloatKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	oneFloat := &Float{Value: 1.0}
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+
+	if one.HashKey() != oneFloat.HashKey() {
+		t.Errorf("integral float has different hash key from integer")
+	}
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+	if half1.HashKey() == oneFloat.HashKey() {
+		t.Errorf("floats with different value have same hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1, "1.0"},
+		{3.14, "3.14"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{1e-9, "1e-09"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Inspect() wrong. expected=%q, got=%q", tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if _, ok := inner.Assign("x", &Integer{Value: 2}); !ok {
+		t.Fatalf("Assign to outer binding failed")
+	}
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("Assign created a binding in the inner scope")
+	}
+	if val, _ := outer.Get("x"); val.(*Integer).Value != 2 {
+		t.Errorf("outer binding was not updated. got=%d", val.(*Integer).Value)
+	}
+
+	if _, ok := inner.Assign("y", &Integer{Value: 1}); ok {
+		t.Errorf("Assign to undefined name succeeded")
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected int64
+	}{
+		{&Range{Start: 0, Stop: 10, Step: 1}, 10},
+		{&Range{Start: 0, Stop: 10, Step: 3}, 4},
+		{&Range{Start: 10, Stop: 0, Step: -3}, 4},
+		{&Range{Start: 5, Stop: 5, Step: 1}, 0},
+		{&Range{Start: 5, Stop: 0, Step: 1}, 0},
+		{&Range{Start: 0, Stop: 5, Step: -1}, 0},
+	}
+
+	for _, tt := range tests {
+		if tt.r.Len() != tt.expected {
+			t.Errorf("%s.Len() wrong. expected=%d, got=%d", tt.r.Inspect(), tt.expected, tt.r.Len())
+		}
+	}
+}
+
+func TestHashSortedPairs(t *testing.T) {
+	keys := []Object{
+		&String{Value: "b"},
+		&Integer{Value: 10},
+		&String{Value: "a"},
+		&Boolean{Value: true},
+		&Float{Value: 2.5},
+		&Integer{Value: -1},
+		&Boolean{Value: false},
+	}
+	h := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range keys {
+		h.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: &Null{}}
+	}
+
+	expected := []string{"false", "true", "-1", "2.5", "10", "a", "b"}
+	pairs := h.SortedPairs()
+	for i, pair := range pairs {
+		if pair.Key.Inspect() != expected[i] {
+			t.Errorf("pairs[%d] wrong. expected=%s, got=%s", i, expected[i], pair.Key.Inspect())
+		}
+	}
+}
+
+func TestErrorTraceback(t *testing.T) {
+	inner := StackFrame{Function: "inner", Pos: token.Position{Filename: "main.mk", Line: 1, Column: 25}}
+	outer := StackFrame{Function: "outer", Pos: token.Position{Filename: "main.mk", Line: 2, Column: 24}}
+	anonymous := StackFrame{Pos: token.Position{Filename: "main.mk", Line: 3, Column: 1}}
+
+	tests := []struct {
+		stack    []StackFrame
+		expected []string
+	}{
+		{nil, nil},
+		{[]StackFrame{outer, anonymous}, []string{
+			"in outer called at main.mk:2:24",
+			"in anonymous function called at main.mk:3:1",
+		}},
+		{[]StackFrame{inner, inner, inner, outer, inner}, []string{
+			"in inner called at main.mk:1:25 (3 times)",
+			"in outer called at main.mk:2:24",
+			"in inner called at main.mk:1:25",
+		}},
+		// 相互再帰で交互に続く呼び出しは、内側と外側の10行ずつを残して間を省く
+		{alternating(inner, outer, 10000), append(append(
+			alternatingLines(inner, outer, 10),
+			"... 9980 more frames"),
+			alternatingLines(inner, outer, 10)...)},
+	}
+
+	for _, tt := range tests {
+		err := &Error{Message: "boom", Stack: tt.stack}
+		traceback := err.Traceback()
+		if len(traceback) != len(tt.expected) {
+			t.Errorf("wrong traceback length. expected=%d, got=%d (%q)", len(tt.expected), len(traceback), traceback)
+			continue
+		}
+		for i, line := range traceback {
+			if line != tt.expected[i] {
+				t.Errorf("traceback[%d] wrong. expected=%q, got=%q", i, tt.expected[i], line)
+			}
+		}
+
+		notes := err.Diagnostic().Notes
+		if len(notes) != len(traceback) {
+			t.Errorf("diagnostic notes wrong. expected=%q, got=%q", traceback, notes)
+		}
+	}
+}
+
+func alternating(a, b StackFrame, n int) []StackFrame {
+	stack := make([]StackFrame, n)
+	for i := range stack {
+		if i%2 == 0 {
+			stack[i] = a
+		} else {
+			stack[i] = b
+		}
+	}
+	return stack
+}
+
+func alternatingLines(a, b StackFrame, n int) []string {
+	var lines []string
+	for _, frame := range alternating(a, b, n) {
+		lines = append(lines, "in "+frame.String())
+	}
+	return lines
+}
@@ -3,8 +3,10 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/ktny/monkey/ast"
+	"github.com/ktny/monkey/diagnostic"
 	"github.com/ktny/monkey/lexer"
 	"github.com/ktny/monkey/token"
 )
@@ -13,38 +15,83 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // =, +=
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
-	LESSGREATER // >, <
-	SUM         // +
-	PRODUCT     // *
+	LESSGREATER // >, <, >=, <=
+	SUM         // +, -, |, ^
+	PRODUCT     // *, /, %, <<, >>, &
 	PREFIX      // -X, !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.PIPE:            SUM,
+	token.CARET:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.SHIFT_LEFT:      PRODUCT,
+	token.SHIFT_RIGHT:     PRODUCT,
+	token.AMPERSAND:       PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
+// 構文エラーのエラーコード
+const (
+	ErrUnexpectedToken = "E0001" // 期待するトークンではない
+	ErrNoPrefixParseFn = "E0002" // 式の先頭になれないトークン
+	ErrInvalidInteger  = "E0003" // 整数として解釈できないリテラル
+	ErrIllegalToken    = "E0004" // 字句解析できないトークン
+	ErrInvalidFloat    = "E0005" // 浮動小数点数として解釈できないリテラル
+	ErrInvalidParam    = "E0006" // 仮引数の並びが不正
+	ErrInvalidAssign   = "E0007" // 代入できない式への代入
+	ErrOutsideLoop     = "E0008" // ループの外にあるbreak, continue
+	ErrInvalidPattern  = "E0009" // パターンとして解釈できない式
+)
+
+// 1回の構文解析で報告するエラーの上限。これを超えると構文解析を打ち切る
+const maxErrors = 10
+
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
 type Parser struct {
-	l         *lexer.Lexer
-	errors    []string
-	curToken  token.Token
-	peekToken token.Token
+	l           *lexer.Lexer
+	diagnostics []diagnostic.Diagnostic
+	curToken    token.Token
+	peekToken   token.Token
+
+	// curToken, peekTokenの直前にあるドキュメントコメント。なければnil
+	curDoc  *ast.CommentGroup
+	peekDoc *ast.CommentGroup
+
+	// 構文エラーが発生し、次の文まで同期するのを待っている状態か否か。この間のエラーは報告しない
+	panicking bool
+	// curTokenまでの波括弧のネストの深さ
+	depth int
+	// 解析中の関数本体の中でのループのネストの深さ。break, continueを書けるか否かの判定に使う
+	loopDepth int
 
 	// トークンに対応した前置、中置構文解析関数のマップ
 	prefixParseFns map[token.TokenType]prefixParseFn
@@ -53,32 +100,51 @@ type Parser struct {
 
 // New Parserインスタンスを返す
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, diagnostics: []diagnostic.Diagnostic{}}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -88,15 +154,113 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Errors Parserインスタンスが持つエラーを返す
+// Errors Parserインスタンスが持つエラーを位置情報付きの文字列で返す
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+	for _, d := range p.diagnostics {
+		errors = append(errors, d.Error())
+	}
+	return errors
+}
+
+// Incomplete 最後の構文エラーが入力の終わりで起きたか否かを返す
+// 閉じられていない括弧や生文字列のように、入力が続けば解析できる可能性がある。REPLはこれを見て次の行を読む
+func (p *Parser) Incomplete() bool {
+	if len(p.diagnostics) == 0 || !p.curTokenIs(token.EOF) {
+		return false
+	}
+	return p.diagnostics[len(p.diagnostics)-1].End.Offset >= p.curToken.Pos.Offset
+}
+
+// Diagnostics Parserインスタンスが持つエラーを構造化された診断として返す
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	return p.diagnostics
+}
+
+// tokの範囲を指す構文エラーを追加し、追加したエラーを返す
+// 同期待ちの間に発生した連鎖的なエラーや上限を超えたエラーは追加せずnilを返す
+func (p *Parser) errorAt(tok token.Token, code string, msg string, hints ...string) *diagnostic.Diagnostic {
+	if p.panicking || len(p.diagnostics) >= maxErrors {
+		p.panicking = true
+		return nil
+	}
+	p.panicking = true
+
+	p.diagnostics = append(p.diagnostics, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     code,
+		Message:  msg,
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Hints:    hints,
+	})
+	return &p.diagnostics[len(p.diagnostics)-1]
 }
 
 // lexerで解析して取り出したTokenをcurToken, peekTokenに入れる
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curDoc = p.peekDoc
+	p.peekToken, p.peekDoc = p.readToken()
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.depth++
+	case token.RBRACE:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
+}
+
+// lexerからコメント以外の次のトークンを読む
+// トークンの直前の行まで連続するコメントがあれば、ドキュメントコメントとして合わせて返す
+func (p *Parser) readToken() (token.Token, *ast.CommentGroup) {
+	prev := p.curToken
+	var group *ast.CommentGroup
+
+	for {
+		tok := p.l.NextToken()
+		if tok.Type != token.COMMENT {
+			if group != nil && group.End().Line+1 < tok.Pos.Line {
+				group = nil
+			}
+			return tok, group
+		}
+
+		comment := &ast.Comment{Token: tok}
+		switch {
+		case group == nil && prev.End.IsValid() && prev.End.Line == tok.Pos.Line:
+			// 前のトークンと同じ行にある行末のコメントはドキュメントコメントにしない
+		case group == nil || group.End().Line+1 < tok.Pos.Line:
+			group = &ast.CommentGroup{List: []*ast.Comment{comment}}
+		default:
+			group.List = append(group.List, comment)
+		}
+	}
+}
+
+// 構文エラーの後、次の文の先頭の直前までトークンを読み飛ばす
+// depthは文を読み始めた時点の波括弧のネストの深さで、文の途中で開かれた波括弧の中では同期しない
+func (p *Parser) synchronize(depth int) {
+	for !p.curTokenIs(token.EOF) && !p.peekTokenIs(token.EOF) {
+		// 文が外側のブロックを閉じる } まで読み進めてしまった
+		if p.depth < depth {
+			return
+		}
+
+		if p.depth == depth {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE, token.RBRACE:
+				return
+			}
+		}
+
+		p.nextToken()
+	}
 }
 
 // curTokenが指定のTokenTypeか否かを返す
@@ -110,9 +274,12 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 // peekTokenが指定のTokenでない場合はParserインスタンスのErrorに追加する
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instaed", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(t token.TokenType) *diagnostic.Diagnostic {
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
+	if p.peekTokenIs(token.EOF) {
+		return p.errorAt(p.peekToken, ErrUnexpectedToken, msg, "the input ended before the construct was complete")
+	}
+	return p.errorAt(p.peekToken, ErrUnexpectedToken, msg)
 }
 
 // peekTokenが期待するものか否かを返す。期待するものであればトークンをすすめる
@@ -126,6 +293,24 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// peekTokenが閉じ括弧tであればトークンをすすめる。そうでなければ対応する開き括弧openの位置を添えてエラーを追加する
+func (p *Parser) expectClosing(t token.TokenType, open token.Token) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+
+	if d := p.peekError(t); d != nil {
+		d.Notes = append(d.Notes, fmt.Sprintf("unclosed %s opened at %s", open.Literal, open.Pos))
+	}
+	return false
+}
+
+// startから現在のトークンまでを解析できなかった式として返す
+func (p *Parser) badExpression(start token.Token) *ast.BadExpression {
+	return &ast.BadExpression{Token: start, To: p.curToken.End}
+}
+
 // peekTokenの優先順位を返す
 func (p *Parser) peekPrecedence() int {
 	if p, ok := precedences[p.peekToken.Type]; ok {
@@ -157,7 +342,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
-	for !p.curTokenIs(token.EOF) {
+	for !p.curTokenIs(token.EOF) && len(p.diagnostics) < maxErrors {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
@@ -169,42 +354,194 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 // 構文解析して文を返す
+// 構文エラーが発生した場合は次の文の直前まで読み飛ばし、文を組み立てられなければBadStatementを返す
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+	depth := p.depth
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if let := p.parseLetStatement(); let != nil {
+			stmt = let
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.WHILE:
+		if while := p.parseWhileStatement(); while != nil {
+			stmt = while
+		}
+	case token.FOR:
+		if forStmt := p.parseForStatement(); forStmt != nil {
+			stmt = forStmt
+		}
+	case token.BREAK, token.CONTINUE:
+		stmt = p.parseLoopControlStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	return p.recoverStatement(start, depth, stmt)
+}
+
+// startから読み始めた文の構文解析を終える。構文エラーがあれば次の文の直前まで読み飛ばす
+// 文を組み立てられなかった場合（stmtがnil）は不正な文を返す
+func (p *Parser) recoverStatement(start token.Token, depth int, stmt ast.Statement) ast.Statement {
+	if !p.panicking {
+		return stmt
+	}
+
+	p.synchronize(depth)
+	p.panicking = false
+
+	if stmt == nil {
+		return &ast.BadStatement{Token: start, To: p.curToken.End}
 	}
+	return stmt
 }
 
 // 構文解析してlet文を返す
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Doc: p.curDoc}
+
+	// let [a, b] = xs; と let {name} = h; は分割代入
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+		if lit := findLiteralPattern(stmt.Pattern); lit != nil {
+			msg := fmt.Sprintf("literal pattern %s is not allowed in let", lit)
+			if d := p.errorAt(p.curToken, ErrInvalidPattern, msg); d != nil {
+				d.Pos, d.End = lit.Pos(), lit.End()
+			}
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	// 関数には束縛先の名前を持たせ、エラーメッセージで使う
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// 構文解析してwhile文を返す
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lparen := p.curToken
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// 構文解析してfor文を返す
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lparen := p.curToken
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if !p.expectPeek(token.ASSIGN) {
+	if !p.expectPeek(token.IN) {
 		return nil
 	}
 
 	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
 
-	stmt.Value = p.parseExpression(LOWEST)
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil
+	}
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
+// ループの本体のブロックを構文解析する。本体の中ではbreak, continueを書ける
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+// 構文解析してbreak文またはcontinue文を返す
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+
+	if p.loopDepth == 0 {
+		p.errorAt(tok, ErrOutsideLoop, fmt.Sprintf("%s is not in a loop", tok.Literal))
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
 // 構文解析してreturn文を返す
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -213,7 +550,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
 		p.nextToken()
 	}
 
@@ -229,7 +566,8 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt.Expression = p.parseExpression(LOWEST)
 
 	// セミコロンがあればトークンを進める。なくてもセミコロン省略可能になるだけなので問題ない
-	if p.peekTokenIs(token.SEMICOLON) {
+	// 構文エラーの後はセミコロンの扱いを同期処理に任せる
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
 		p.nextToken()
 	}
 
@@ -244,13 +582,14 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefixParseFn := p.prefixParseFns[p.curToken.Type]
 	if prefixParseFn == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return p.badExpression(p.curToken)
 	}
 	leftExp := prefixParseFn()
 
 	// peekTokenの優先順位が現在のもの以下であればループしない
 	// これによりASTを演算子の優先順位で正しくネストする
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	// 構文エラーが発生していればそれ以上式を組み立てない
+	for !p.peekTokenIs(token.SEMICOLON) && !p.panicking && precedence < p.peekPrecedence() {
 		infixParseFn := p.infixParseFns[p.peekToken.Type]
 		if infixParseFn == nil {
 			return leftExp
@@ -267,7 +606,13 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 // トークンに対応する前置構文解析関数がないエラーを追加する
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, ErrNoPrefixParseFn, msg, "an expression was expected here")
+}
+
+// 字句解析できなかったトークンのエラーを追加する
+func (p *Parser) parseIllegal() ast.Expression {
+	p.errorAt(p.curToken, ErrIllegalToken, p.curToken.Literal)
+	return p.badExpression(p.curToken)
 }
 
 // 構文解析して式（識別子）を返す
@@ -281,11 +626,81 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	value, msg := parseInteger(p.curToken.Literal)
+	if msg != "" {
+		p.errorAt(p.curToken, ErrInvalidInteger, msg)
+		return p.badExpression(p.curToken)
+	}
+
+	lit.Value = value
+	return lit
+}
+
+// 整数リテラルを接頭辞（0x, 0o, 0b）の示す基数で解釈して返す
+// 解釈できない場合はエラーメッセージを返す
+func parseInteger(literal string) (int64, string) {
+	base, kind, digits := 10, "decimal", literal
+	if len(literal) >= 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base, kind = 16, "hexadecimal"
+		case 'o', 'O':
+			base, kind = 8, "octal"
+		case 'b', 'B':
+			base, kind = 2, "binary"
+		}
+		if base != 10 {
+			digits = literal[2:]
+		}
+	}
+
+	if digits == "" {
+		return 0, fmt.Sprintf("%s literal %s has no digits", kind, literal)
+	}
+
+	// _ は数字と数字の間にのみ置ける
+	for i := 0; i < len(digits); i++ {
+		ch := digits[i]
+		if ch == '_' {
+			if i == 0 || i == len(digits)-1 || digits[i+1] == '_' {
+				return 0, fmt.Sprintf("'_' must separate successive digits in %s", literal)
+			}
+			continue
+		}
+		if digitValue(ch) >= base {
+			return 0, fmt.Sprintf("invalid digit '%c' in %s literal %s", ch, kind, literal)
+		}
+	}
+
+	value, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		return 0, fmt.Sprintf("integer literal %s overflows int64", literal)
+	}
+	return value, ""
+}
+
+// 1桁の数字の値を返す。数字でなければ36を返す
+func digitValue(ch byte) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch - 'a' + 10)
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch - 'A' + 10)
+	}
+	return 36
+}
+
+// 構文解析して式（浮動小数点数リテラル）を返す
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.errorAt(p.curToken, ErrInvalidFloat, msg)
+		return p.badExpression(p.curToken)
 	}
 
 	lit.Value = value
@@ -337,14 +752,39 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// 構文解析して式（代入式）を返す。右結合なので a = b = c は a = (b = c) になる
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("cannot assign to %s", target.String())
+		if d := p.errorAt(p.curToken, ErrInvalidAssign, msg); d != nil {
+			d.Pos, d.End = target.Pos(), target.End()
+		}
+		return p.badExpression(p.curToken)
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 // 構文解析して式（グループ式）を返す
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curToken
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return p.badExpression(lparen)
 	}
 
 	return exp
@@ -355,18 +795,19 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
+	lparen := p.curToken
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return p.badExpression(expression.Token)
 	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
 	expression.Consequence = p.parseBlockStatement()
@@ -374,8 +815,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		// else if は後続のif式だけを持つブロックとして扱う
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			ifTok := p.curToken
+			nested := p.parseIfExpression()
+			expression.Alternative = &ast.BlockStatement{
+				Token:      ifTok,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: ifTok, Expression: nested}},
+			}
+			if p.curTokenIs(token.RBRACE) {
+				expression.Alternative.Rbrace = p.curToken
+			}
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
-			return nil
+			return p.badExpression(expression.Token)
 		}
 
 		expression.Alternative = p.parseBlockStatement()
@@ -384,19 +840,271 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// 構文解析して式（match式）を返す
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return p.badExpression(expression.Token)
+	}
+
+	lparen := p.curToken
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return p.badExpression(expression.Token)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression(expression.Token)
+	}
+	lbrace := p.curToken
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return p.badExpression(expression.Token)
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		// 腕の区切りのカンマは省略できる
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+		if p.peekTokenIs(token.EOF) {
+			break
+		}
+	}
+
+	if !p.expectClosing(token.RBRACE, lbrace) {
+		return p.badExpression(expression.Token)
+	}
+
+	expression.Rbrace = p.curToken
+	return expression
+}
+
+// 構文解析してmatch式の腕（pattern if guard => body）を返す。本体が { で始まればブロックになる
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		arm.Body = p.parseMatchArmBlockOrHash()
+	} else {
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+	}
+
+	return arm
+}
+
+// curTokenの { から始まるmatch式の腕の本体を構文解析してブロック文またはハッシュリテラルを返す
+// 最初の式の後に : が続けばハッシュリテラル（_ => {"a": 1}）、それ以外はブロック文とみなす。{} は空のブロック
+func (p *Parser) parseMatchArmBlockOrHash() ast.Node {
+	switch p.peekToken.Type {
+	case token.RBRACE, token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+		return p.parseBlockStatement()
+	}
+
+	lbrace := p.curToken
+	depth := p.depth
+
+	p.nextToken()
+	start := p.curToken
+	first := p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.COLON) && !p.panicking {
+		hash := &ast.HashLiteral{Token: lbrace, Pairs: make(map[ast.Expression]ast.Expression)}
+		if !p.parseHashPair(hash, first) {
+			return p.badExpression(lbrace)
+		}
+		return p.parseHashLiteralRest(hash)
+	}
+
+	// 最初の式はブロックの最初の式文になる
+	stmt := &ast.ExpressionStatement{Token: start, Expression: first}
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	block := &ast.BlockStatement{Token: lbrace}
+	block.Statements = []ast.Statement{p.recoverStatement(start, depth, stmt)}
+	if p.depth >= depth {
+		p.nextToken()
+	}
+	return p.parseBlockStatementRest(block, depth)
+}
+
+// 構文解析してcurTokenから始まるパターンを返す。パターンとして解釈できなければエラーを追加してnilを返す
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.IdentifierPattern{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.prefixParseFns[p.curToken.Type]()}
+	case token.MINUS:
+		if p.peekTokenIs(token.INT) || p.peekTokenIs(token.FLOAT) {
+			return &ast.LiteralPattern{Value: p.parsePrefixExpression()}
+		}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+
+	p.errorAt(p.curToken, ErrInvalidPattern, fmt.Sprintf("expected pattern, got %s", p.curToken.Type))
+	return nil
+}
+
+// pattern中の最初のリテラルのパターンを返す。なければnilを返す
+func findLiteralPattern(pattern ast.Pattern) *ast.LiteralPattern {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return pattern
+	case *ast.ArrayPattern:
+		for _, el := range pattern.Elements {
+			if lit := findLiteralPattern(el); lit != nil {
+				return lit
+			}
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			if lit := findLiteralPattern(pair.Value); lit != nil {
+				return lit
+			}
+		}
+	}
+	return nil
+}
+
+// 構文解析して配列のパターン（[a, b, ...rest]）を返す
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = p.parsePattern()
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.errorAt(p.peekToken, ErrInvalidPattern, "rest pattern must be the last element")
+				return nil
+			}
+			break
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectClosing(token.RBRACKET, pattern.Token) {
+		return nil
+	}
+
+	pattern.Rbracket = p.curToken
+	return pattern
+}
+
+// 構文解析してハッシュのパターン（{"key": pattern, name}）を返す
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		pair := &ast.HashPatternPair{}
+		switch p.curToken.Type {
+		case token.IDENT:
+			// {name} は {"name": name} の省略形
+			pair.Key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			pair.Value = p.parsePattern()
+		case token.INT, token.STRING, token.TRUE, token.FALSE:
+			pair.Key = p.prefixParseFns[p.curToken.Type]()
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			if pair.Value = p.parsePattern(); pair.Value == nil {
+				return nil
+			}
+		default:
+			msg := fmt.Sprintf("expected hash pattern key, got %s", p.curToken.Type)
+			p.errorAt(p.curToken, ErrInvalidPattern, msg)
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectClosing(token.RBRACE, pattern.Token) {
+		return nil
+	}
+
+	pattern.Rbrace = p.curToken
+	return pattern
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	depth := p.depth
 
 	p.nextToken()
+	return p.parseBlockStatementRest(block, depth)
+}
 
+// curTokenからブロックの残りの文を閉じる } まで構文解析してblockに加える
+// depthはブロックの { を読んだ時点の波括弧のネストの深さ
+func (p *Parser) parseBlockStatementRest(block *ast.BlockStatement, depth int) *ast.BlockStatement {
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		// 構文エラーのある文がこのブロックを閉じる } まで読み進めていればブロックを終える
+		if p.depth < depth {
+			break
+		}
 		p.nextToken()
 	}
+
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	} else if d := p.errorAt(p.curToken, ErrUnexpectedToken, "expected } to close block, got EOF instead"); d != nil {
+		d.Notes = append(d.Notes, fmt.Sprintf("unclosed { opened at %s", block.Token.Pos))
+	}
 	return block
 }
 
@@ -404,61 +1112,110 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	lit.Parameters, lit.Defaults, lit.Rest = p.parseFunctionParameters()
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
+	// 関数の本体からは外側のループをbreak, continueできない
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+// 構文解析して仮引数の並びを返す。既定値（y = 10）と末尾の残りの引数（...rest）を受け付ける
+// 既定値は1つもなければnil、あれば仮引数と同じ長さで既定値のない仮引数の位置をnilにして返す
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Expression, *ast.Identifier) {
 	identifiers := []*ast.Identifier{}
+	var defaults []ast.Expression
+	var rest *ast.Identifier
+	lparen := p.curToken
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil, nil
 	}
 
-	p.nextToken()
+	for {
+		p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil, nil, nil
+			}
+			rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.COMMA) {
+				p.errorAt(p.curToken, ErrInvalidParam, "rest parameter must be the last parameter")
+				return nil, nil, nil
+			}
+			break
+		}
+
+		if !p.curTokenIs(token.IDENT) {
+			msg := fmt.Sprintf("expected parameter name, got %s instead", p.curToken.Type)
+			p.errorAt(p.curToken, ErrUnexpectedToken, msg)
+			return nil, nil, nil
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			if defaults == nil {
+				defaults = make([]ast.Expression, len(identifiers)-1)
+			}
+			defaults = append(defaults, p.parseExpression(LOWEST))
+		} else if defaults != nil {
+			msg := fmt.Sprintf("parameter %s without default value follows parameter with default value", ident.Value)
+			p.errorAt(ident.Token, ErrInvalidParam, msg)
+			return nil, nil, nil
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil, nil, nil
 	}
 
-	return identifiers
+	return identifiers, defaults, rest
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return p.badExpression(exp.Token)
+	}
+	exp.Rparen = p.curToken
 	return exp
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return p.badExpression(array.Token)
+	}
+	array.Rbracket = p.curToken
 	return array
 }
 
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
+	open := p.curToken
 
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -466,74 +1223,129 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
-	if !p.expectPeek(end) {
+	// 閉じ括弧がなければnilを返す。呼び出し側は不正な式として扱う
+	if !p.expectClosing(end, open) {
 		return nil
 	}
 
 	return list
 }
 
+// 構文解析して呼び出しの引数または配列リテラルの要素を返す。...で始まれば展開する式になる
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
+// 構文解析して添字式（a[i]）またはスライス式（a[start:stop]）を返す
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	lbracket := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(lbracket, left, index)
+	}
+
+	exp := &ast.IndexExpression{Token: lbracket, Left: left, Index: index}
+
+	if !p.expectClosing(token.RBRACKET, exp.Token) {
+		return p.badExpression(exp.Token)
+	}
 
+	exp.Rbracket = p.curToken
+	return exp
+}
+
+// 構文解析してstartの後の : からスライス式を返す
+func (p *Parser) parseSliceExpression(lbracket token.Token, left, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: lbracket, Left: left, Start: start}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RBRACKET) {
-		return nil
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.Stop = p.parseExpression(LOWEST)
 	}
 
+	if !p.expectClosing(token.RBRACKET, exp.Token) {
+		return p.badExpression(exp.Token)
+	}
+
+	exp.Rbracket = p.curToken
 	return exp
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	return p.parseHashLiteralRest(hash)
+}
 
+// ハッシュリテラルの残りの組を閉じる } まで構文解析してhashに加える
+func (p *Parser) parseHashLiteralRest(hash *ast.HashLiteral) ast.Expression {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
-
-		if !p.expectPeek(token.COLON) {
-			return nil
+		if !p.parseHashPair(hash, p.parseExpression(LOWEST)) {
+			return p.badExpression(hash.Token)
 		}
+	}
 
-		p.nextToken()
-		value := p.parseExpression(LOWEST)
+	if !p.expectClosing(token.RBRACE, hash.Token) {
+		return p.badExpression(hash.Token)
+	}
 
-		hash.Pairs[key] = value
+	hash.Rbrace = p.curToken
+	return hash
+}
 
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
-		}
+// keyに続く : と値を構文解析してhashに加え、組を区切る , まで読み進める。構文エラーがあればfalseを返す
+func (p *Parser) parseHashPair(hash *ast.HashLiteral, key ast.Expression) bool {
+	if !p.expectPeek(token.COLON) {
+		return false
 	}
 
-	if !p.expectPeek(token.RBRACE) {
-		return nil
-	}
+	p.nextToken()
+	hash.Pairs[key] = p.parseExpression(LOWEST)
 
-	return hash
+	return p.peekTokenIs(token.RBRACE) || p.expectPeek(token.COMMA)
 }
 
 func (p *Parser) parseMacroLiteral() ast.Expression {
 	lit := &ast.MacroLiteral{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	// マクロの引数は評価されないASTなので、既定値と残りの引数は受け付けない
+	var defaults []ast.Expression
+	var rest *ast.Identifier
+	lit.Parameters, defaults, rest = p.parseFunctionParameters()
+	if defaults != nil || rest != nil {
+		p.errorAt(lit.Token, ErrInvalidParam, "macro parameters cannot have default values or a rest parameter")
+		return p.badExpression(lit.Token)
+	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
 	lit.Body = p.parseBlockStatement()
@@ -55,6 +55,51 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, _, ...rest] = xs", "let [a, _, ...rest] = xs;"},
+		{"let {name, age} = person;", "let {name:name, age:age} = person;"},
+		{`let {"pos": [x, y]} = h;`, "let {pos:[x, y]} = h;"},
+		{"let [f] = [fn() { 1 }];", "let [f] = [fn() 1];"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = xs;", "1:9: literal pattern 1 is not allowed in let"},
+		{`let {"k": "v"} = h;`, "1:11: literal pattern v is not allowed in let"},
+		{"let [a + 1] = xs;", "1:8: expected next token to be ,, got + instead"},
+		{"let [a] xs;", "1:9: expected next token to be =, got IDENT instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 // return文の構文解析テスト
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
@@ -164,6 +209,246 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xff", 255},
+		{"0XFF", 255},
+		{"0o755", 493},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0xdead_beef", 0xdeadbeef},
+		{"0b1111_0000", 240},
+		{"010", 10},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%s - literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+		if literal.TokenLiteral() != tt.input {
+			t.Errorf("%s - literal.TokenLiteral not %s. got=%s", tt.input, tt.input, literal.TokenLiteral())
+		}
+	}
+}
+
+func TestInvalidIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0x", "hexadecimal literal 0x has no digits"},
+		{"0b102", "invalid digit '2' in binary literal 0b102"},
+		{"0o8", "invalid digit '8' in octal literal 0o8"},
+		{"0xfg", "invalid digit 'g' in hexadecimal literal 0xfg"},
+		{"1__000", "'_' must separate successive digits in 1__000"},
+		{"1000_", "'_' must separate successive digits in 1000_"},
+		{"0x_ff", "'_' must separate successive digits in 0x_ff"},
+		{"9223372036854775808", "integer literal 9223372036854775808 overflows int64"},
+		{"0xffffffffffffffff", "integer literal 0xffffffffffffffff overflows int64"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) != 1 {
+			t.Errorf("%s - expected 1 diagnostic, got=%d", tt.input, len(diags))
+			continue
+		}
+		if diags[0].Code != ErrInvalidInteger {
+			t.Errorf("%s - wrong code. expected=%s, got=%s", tt.input, ErrInvalidInteger, diags[0].Code)
+		}
+		if diags[0].Message != tt.expected {
+			t.Errorf("%s - wrong message. expected=%q, got=%q", tt.input, tt.expected, diags[0].Message)
+		}
+	}
+}
+
+func TestAssignExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "(x = 5)"},
+		{"x = y = 5", "(x = (y = 5))"},
+		{"x += 1 * 2", "(x += (1 * 2))"},
+		{"x -= 1", "(x -= 1)"},
+		{"x *= 2", "(x *= 2)"},
+		{"x /= 2", "(x /= 2)"},
+		{"x = a || b", "(x = (a || b))"},
+		{"let y = x = 1;", "let y = (x = 1);"},
+		{"f(x = 1)", "f((x = 1))"},
+		{"a[0] = 1", "((a[0]) = 1)"},
+		{`h["k"] += a[1]`, "((h[k]) += (a[1]))"},
+		{"m[i][j] = 0", "(((m[i])[j]) = 0)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedMsg string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"1 = 2", "cannot assign to 1", "1:1", "1:2"},
+		{"a + b = c", "cannot assign to (a + b)", "1:1", "1:6"},
+		{"f() += 1", "cannot assign to f()", "1:1", "1:4"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) != 1 {
+			t.Errorf("expected 1 diagnostic for %q, got=%d", tt.input, len(diags))
+			continue
+		}
+		d := diags[0]
+		if d.Code != ErrInvalidAssign {
+			t.Errorf("wrong code for %q. expected=%s, got=%s", tt.input, ErrInvalidAssign, d.Code)
+		}
+		if d.Message != tt.expectedMsg {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tt.input, tt.expectedMsg, d.Message)
+		}
+		if d.Pos.String() != tt.expectedPos || d.End.String() != tt.expectedEnd {
+			t.Errorf("wrong span for %q. expected=%s-%s, got=%s-%s", tt.input, tt.expectedPos, tt.expectedEnd, d.Pos, d.End)
+		}
+	}
+}
+
+func TestLoopStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x += 1 }", "while (x < 10) (x += 1)"},
+		{"while (true) { break; };", "while true break;"},
+		{"for (x in xs) { puts(x) }", "for (x in xs) puts(x)"},
+		{"for (i in range(10)) { if (i == 2) { continue } }", "for (i in range(10)) if(i == 2) continue;"},
+		{"while (a) { for (b in c) { break } break }", "while a for (b in c) break;break;"},
+		{"while (a) { let x = 1 }; let y = 2;", "while a let x = 1;let y = 2;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("for (x in xs) { x }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if !testIdentifier(t, stmt.Iterable, "xs") {
+		return
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break is not in a loop"},
+		{"continue", "1:1: continue is not in a loop"},
+		{"if (true) { break }", "1:13: break is not in a loop"},
+		{"while (true) { let f = fn() { break }; }", "1:31: break is not in a loop"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+			continue
+		}
+		if p.Diagnostics()[0].Code != ErrOutsideLoop {
+			t.Errorf("wrong code for %q. expected=%s, got=%s", tt.input, ErrOutsideLoop, p.Diagnostics()[0].Code)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+3;", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 // 真偽値の構文解析テスト
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
@@ -209,6 +494,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		{"-15", "-", 15},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
+		{"~5;", "~", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -259,6 +545,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"false || true", false, "||", true},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -335,6 +631,17 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a < b && c == d", "((a < b) && (c == d))"},
+		{"!a || b", "((!a) || b)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a ^ b << 2", "(a ^ (b << 2))"},
+		{"1 << 2 + 3", "((1 << 2) + 3)"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"~a & b", "((~a) & b)"},
 	}
 
 	for _, tt := range tests {
@@ -453,6 +760,109 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative.Statements does not contain 1 statements. got=%d\n",
+			len(exp.Alternative.Statements))
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+			exp.Alternative.Statements[0])
+	}
+
+	nested, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+
+	if !testInfixExpression(t, nested.Condition, "x", "==", 0) {
+		return
+	}
+
+	if nested.Alternative == nil {
+		t.Fatalf("nested.Alternative is nil")
+	}
+
+	if got := exp.End().String(); got != "1:52" {
+		t.Errorf("exp.End() wrong. expected=%q, got=%q", "1:52", got)
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => \"one\", _ => \"other\" }", "match x { 1 => one, _ => other }"},
+		{"match (x) { -1 => a 2.5 => b true => c }", "match x { (-1) => a, 2.5 => b, true => c }"},
+		{"match (x) { n if n > 0 => n }", "match x { n if (n > 0) => n }"},
+		{"match (xs) { [] => 0, [a, _, ...rest] => a }", "match xs { [] => 0, [a, _, ...rest] => a }"},
+		{"match (h) { {\"k\": [v], name} => { v; name } }", "match h { {k:[v], name:name} => vname }"},
+		{"match (x) { }", "match x {  }"},
+		// { の後の最初の式に : が続けばハッシュリテラル、それ以外はブロック
+		{"match (x) { _ => {\"a\": 1} }", "match x { _ => {a:1} }"},
+		{"match (x) { _ => {k: [1]}, 1 => 2 }", "match x { _ => {k:[1]}, 1 => 2 }"},
+		{"match (x) { _ => { x } }", "match x { _ => x }"},
+		{"match (x) { _ => { x; y } }", "match x { _ => xy }"},
+		{"match (x) { _ => { let y = 1; y } }", "match x { _ => let y = 1;y }"},
+		{"match (x) { _ => {} }", "match x { _ =>  }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { x + 1 => 1 }", "1:15: expected next token to be =>, got + instead"},
+		{"match (x) { (1) => 1 }", "1:13: expected pattern, got ("},
+		{"match (x) { [...a, b] => 1 }", "1:18: rest pattern must be the last element"},
+		{"match (x) { {fn() {}} => 1 }", "1:14: expected hash pattern key, got FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -496,6 +906,33 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong. want 'myFunction', got=%q", function.Name)
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -525,6 +962,114 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults []string
+		expectedRest     string
+	}{
+		{"fn(x, y = 10) {};", []string{"x", "y"}, []string{"", "10"}, ""},
+		{"fn(x = 1, y = x * 2) {};", []string{"x", "y"}, []string{"1", "(x * 2)"}, ""},
+		{"fn(...args) {};", []string{}, nil, "args"},
+		{"fn(x, y = 10, ...rest) {};", []string{"x", "y"}, []string{"", "10"}, "rest"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Fatalf("length defaults wrong. want %d, got=%d", len(tt.expectedDefaults), len(function.Defaults))
+		}
+		for i, expected := range tt.expectedDefaults {
+			actual := ""
+			if function.Defaults[i] != nil {
+				actual = function.Defaults[i].String()
+			}
+			if actual != expected {
+				t.Errorf("defaults[%d] wrong. want %q, got=%q", i, expected, actual)
+			}
+		}
+
+		rest := ""
+		if function.Rest != nil {
+			rest = function.Rest.Value
+		}
+		if rest != tt.expectedRest {
+			t.Errorf("rest parameter wrong. want %q, got=%q", tt.expectedRest, rest)
+		}
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode string
+		expectedMsg  string
+	}{
+		{"fn(x = 1, y) {}", ErrInvalidParam, "parameter y without default value follows parameter with default value"},
+		{"fn(...rest, x) {}", ErrInvalidParam, "rest parameter must be the last parameter"},
+		{"fn(1) {}", ErrUnexpectedToken, "expected parameter name, got INT instead"},
+		{"macro(x = 1) {}", ErrInvalidParam, "macro parameters cannot have default values or a rest parameter"},
+		{"macro(...xs) {}", ErrInvalidParam, "macro parameters cannot have default values or a rest parameter"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) == 0 {
+			t.Errorf("parser has no diagnostics for %q", tt.input)
+			continue
+		}
+		if diags[0].Code != tt.expectedCode {
+			t.Errorf("wrong code for %q. expected=%s, got=%s", tt.input, tt.expectedCode, diags[0].Code)
+		}
+		if diags[0].Message != tt.expectedMsg {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tt.input, tt.expectedMsg, diags[0].Message)
+		}
+	}
+}
+
+func TestSpreadExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...xs)", "f(...xs)"},
+		{"f(1, ...xs, ...ys)", "f(1, ...xs, ...ys)"},
+		{"f(...[1, 2] + ys)", "f(...([1, 2] + ys))"},
+		{"[0, ...xs]", "[0, ...xs]"},
+		{"let f = fn(x, y = 10, ...rest) { x };", "let f = fn(x, y = 10, ...rest) x;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5)`
 
@@ -704,6 +1249,53 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs[1:2]", "(xs[1:2])"},
+		{"xs[1:]", "(xs[1:])"},
+		{"xs[:-1]", "(xs[:(-1)])"},
+		{"xs[:]", "(xs[:])"},
+		{"xs[i + 1:len(xs)][0]", "((xs[(i + 1):len(xs)])[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("myArray[1:2]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	sliceExp, ok := stmt.Expression.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, sliceExp.Left, "myArray") {
+		return
+	}
+	if !testIntegerLiteral(t, sliceExp.Start, 1) {
+		return
+	}
+	if !testIntegerLiteral(t, sliceExp.Stop, 2) {
+		return
+	}
+	if got := sliceExp.End().String(); got != "1:13" {
+		t.Errorf("sliceExp.End() wrong. expected=%q, got=%q", "1:13", got)
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -846,3 +1438,292 @@ func TestMacroLiteralParsing(t *testing.T) {
 
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(x, y) {
+  x + y;
+};
+add(1, [2, 3][0]);`
+
+	tests := []struct {
+		node     func(program *ast.Program) ast.Node
+		expected string // "line:col-line:col"
+	}{
+		{func(program *ast.Program) ast.Node { return program }, "1:1-4:18"},
+		{func(program *ast.Program) ast.Node { return program.Statements[0] }, "1:1-3:2"},
+		{func(program *ast.Program) ast.Node {
+			return program.Statements[0].(*ast.LetStatement).Value
+		}, "1:11-3:2"},
+		{func(program *ast.Program) ast.Node {
+			fn := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+			return fn.Body.Statements[0]
+		}, "2:3-2:8"},
+		{func(program *ast.Program) ast.Node {
+			return program.Statements[1].(*ast.ExpressionStatement).Expression
+		}, "4:1-4:18"},
+		{func(program *ast.Program) ast.Node {
+			call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+			return call.Arguments[1]
+		}, "4:8-4:17"},
+	}
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	for i, tt := range tests {
+		node := tt.node(program)
+		got := fmt.Sprintf("%d:%d-%d:%d", node.Pos().Line, node.Pos().Column, node.End().Line, node.End().Column)
+		if got != tt.expected {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%s, got=%s", i, node.String(), tt.expected, got)
+		}
+	}
+}
+
+func TestParserErrorPosition(t *testing.T) {
+	l := lexer.NewFile("test.mk", "let x 5;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("parser has no errors")
+	}
+
+	expected := "test.mk:1:7: expected next token to be =, got INT instead"
+	if errors[0] != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode string
+		expectedPos  string
+		expectedEnd  string
+	}{
+		{"let x 5;", ErrUnexpectedToken, "1:7", "1:8"},
+		{"let x = 1 + ;", ErrNoPrefixParseFn, "1:13", "1:14"},
+		{"99999999999999999999", ErrInvalidInteger, "1:1", "1:21"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) == 0 {
+			t.Errorf("parser has no diagnostics for %q", tt.input)
+			continue
+		}
+
+		d := diags[0]
+		if d.Code != tt.expectedCode {
+			t.Errorf("wrong code for %q. expected=%s, got=%s", tt.input, tt.expectedCode, d.Code)
+		}
+		if d.Pos.String() != tt.expectedPos || d.End.String() != tt.expectedEnd {
+			t.Errorf("wrong span for %q. expected=%s-%s, got=%s-%s", tt.input, tt.expectedPos, tt.expectedEnd, d.Pos, d.End)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let x = (1 + 2; let y = 3;",
+			[]string{"1:15: expected next token to be ), got ; instead"},
+			[]string{"let x = <bad expression>;", "let y = 3;"},
+		},
+		{
+			"let x 5; let y = 3;",
+			[]string{"1:7: expected next token to be =, got INT instead"},
+			[]string{"<bad statement>", "let y = 3;"},
+		},
+		{
+			`let h = {1: , 2: 3}; let y = 3;`,
+			[]string{"1:13: no prefix parse function for , found"},
+			[]string{"let h = <bad expression>;", "let y = 3;"},
+		},
+		{
+			"let f = fn() { let a = ; a }; f();",
+			[]string{"1:24: no prefix parse function for ; found"},
+			[]string{"let f = fn() let a = <bad expression>;a;", "f()"},
+		},
+		{
+			"let f = fn() { x + }; f();",
+			[]string{"1:20: no prefix parse function for } found"},
+			[]string{"let f = fn() (x + <bad expression>);", "f()"},
+		},
+		{
+			"foo(1 2); bar",
+			[]string{"1:7: expected next token to be ), got INT instead"},
+			[]string{"<bad expression>", "bar"},
+		},
+		{
+			"let xs = [1 2]; let y = 3;",
+			[]string{"1:13: expected next token to be ], got INT instead"},
+			[]string{"let xs = <bad expression>;", "let y = 3;"},
+		},
+		{
+			"if (x { 1 }; 2",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			[]string{"<bad expression>", "2"},
+		},
+		{
+			"let f = fn(x) { x",
+			[]string{"1:18: expected } to close block, got EOF instead"},
+			[]string{"let f = fn(x) x;"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, msg, errors[i])
+			}
+		}
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Errorf("wrong number of statements for %q. expected=%d, got=%d (%q)", tt.input, len(tt.expectedStatements), len(program.Statements), program.String())
+			continue
+		}
+		for i, expected := range tt.expectedStatements {
+			if program.Statements[i].String() != expected {
+				t.Errorf("wrong statement for %q. expected=%q, got=%q", tt.input, expected, program.Statements[i].String())
+			}
+		}
+	}
+}
+
+func TestUnclosedDelimiterNote(t *testing.T) {
+	l := lexer.New("let x = [1, 2;")
+	p := New(l)
+	p.ParseProgram()
+
+	diags := p.Diagnostics()
+	if len(diags) != 1 {
+		t.Fatalf("wrong number of diagnostics. got=%d", len(diags))
+	}
+
+	expected := "unclosed [ opened at 1:9"
+	if len(diags[0].Notes) != 1 || diags[0].Notes[0] != expected {
+		t.Errorf("wrong notes. expected=%q, got=%q", expected, diags[0].Notes)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;\n", false},
+		{"let f = fn(x) {\n", true},
+		{"puts(1,\n", true},
+		{"let xs = [1, 2\n", true},
+		{"if (x) { 1 } else\n", true},
+		{"let s = `a\n", true},
+		{"/* comment\n", true},
+		{"let x = \"a\n", false},
+		{"let = 1; if (x) {\n", true},
+		{"if (x) { 1 }}\n", false},
+		{"let x = 1 +\n", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if p.Incomplete() != tt.expected {
+			t.Errorf("Incomplete() wrong for %q. expected=%t, got=%t (%q)", tt.input, tt.expected, p.Incomplete(), p.Errors())
+		}
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	input := ""
+	for i := 0; i < maxErrors*2; i++ {
+		input += "let = 1;\n"
+	}
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) != maxErrors {
+		t.Errorf("wrong number of errors. expected=%d, got=%d", maxErrors, len(p.Errors()))
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	input := `// add は2つの数を足す
+// 数値以外は渡せない
+let add = fn(x, y) { x + y };
+
+// 空行を挟んだコメントは doc にならない
+
+let a = 1; // 行末のコメントも doc にならない
+let b = 2;
+/**
+ * ブロックコメントの doc
+ */
+let c = add(a, b) / 2;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{
+		"add は2つの数を足す\n数値以外は渡せない",
+		"",
+		"",
+		"ブロックコメントの doc",
+	}
+
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(expected), len(program.Statements))
+	}
+
+	for i, doc := range expected {
+		stmt, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not ast.LetStatement. got=%T", i, program.Statements[i])
+		}
+
+		if stmt.Doc.Text() != doc {
+			t.Errorf("program.Statements[%d] doc wrong. expected=%q, got=%q", i, doc, stmt.Doc.Text())
+		}
+	}
+}
+
+func TestIllegalTokenError(t *testing.T) {
+	l := lexer.New("let x = 1 @ 2;")
+	p := New(l)
+	p.ParseProgram()
+
+	diags := p.Diagnostics()
+	if len(diags) != 1 {
+		t.Fatalf("wrong number of diagnostics. got=%d", len(diags))
+	}
+
+	if diags[0].Code != ErrIllegalToken || diags[0].Message != "unexpected character '@'" {
+		t.Errorf("wrong diagnostic. got=%+v", diags[0])
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/ktny/monkey/diagnostic"
 	"github.com/ktny/monkey/object"
 
 	"github.com/ktny/monkey/evaluator"
@@ -14,33 +16,66 @@ import (
 
 const PROMPT = ">> "
 
-// Start REPLの開始
-func Start(in io.Reader, out io.Writer) {
+// CONTINUATION_PROMPT 入力が途中で終わっているときに続きの行を促すプロンプト
+const CONTINUATION_PROMPT = ".. "
+
+// Start REPLの開始。入力はoptsに従って評価する
+// 閉じられていない括弧などで入力が途中で終わっていれば続きの行を読み、まとめて1つのLexerで解析する
+// 続きの行で空行が入力されたら、その時点の入力の構文エラーを表示する
+func Start(in io.Reader, out io.Writer, opts evaluator.Options) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
 	macroEnv := object.NewEnvironment()
 
+	// 評価した入力のソース。前の入力で定義した関数の中のエラーもその入力のソース行を示せるよう、入力ごとにファイル名を分ける
+	sources := map[string]string{}
+
+	var input strings.Builder
 	for {
-		fmt.Printf(PROMPT)
+		if input.Len() == 0 {
+			fmt.Printf(PROMPT)
+		} else {
+			fmt.Printf(CONTINUATION_PROMPT)
+		}
 		scanned := scanner.Scan()
 		if !scanned {
 			return
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+		continued := input.Len() > 0
+		input.WriteString(line)
+		input.WriteString("\n")
+		src := input.String()
+		filename := fmt.Sprintf("<repl:%d>", len(sources)+1)
+
+		l := lexer.NewReader(filename, strings.NewReader(src))
 		p := parser.New(l)
 
 		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		if len(p.Diagnostics()) != 0 {
+			if p.Incomplete() && !(continued && line == "") {
+				continue
+			}
+			input.Reset()
+			printParseErrors(out, src, p.Diagnostics())
 			continue
 		}
+		input.Reset()
+		sources[filename] = src
 
 		evaluator.DefineMacros(program, macroEnv)
-		expanded := evaluator.ExpandMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacrosWithOptions(program, macroEnv, opts)
+		if err != nil {
+			diagnostic.RenderFiles(out, sources, []diagnostic.Diagnostic{err.Diagnostic()})
+			continue
+		}
 
-		evaluated := evaluator.Eval(expanded, env)
+		evaluated := evaluator.EvalWithOptions(expanded, env, opts)
+		if err, ok := evaluated.(*object.Error); ok {
+			diagnostic.RenderFiles(out, sources, []diagnostic.Diagnostic{err.Diagnostic()})
+			continue
+		}
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -61,11 +96,9 @@ const MONKEY_FACE = `            __,__
            '-----'
 `
 
-func printParseErrors(out io.Writer, errors []string) {
+func printParseErrors(out io.Writer, src string, diags []diagnostic.Diagnostic) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser erros:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
-	}
+	diagnostic.Render(out, src, diags)
 }
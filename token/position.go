@@ -0,0 +1,29 @@
+package token
+
+import "fmt"
+
+// Position ソースコード上の位置
+type Position struct {
+	Filename string // ファイル名。なければ空文字
+	Offset   int    // 先頭からのバイトオフセット。0始まり
+	Line     int    // 行番号。1始まり
+	Column   int    // 列番号。1始まりで文字（コードポイント）単位で数える
+}
+
+// IsValid 位置情報を持っているか否かを返す
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String file:line:column 形式の文字列を返す
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
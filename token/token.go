@@ -5,17 +5,23 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // トークン先頭の位置
+	End     Position // トークン末尾の直後の位置
 }
 
 const (
-	ILLEGAL = "ILLEGAL"
+	ILLEGAL = "ILLEGAL" // 字句解析できないトークン。Literalにはエラーの内容が入る
 	EOF     = "EOF"
 
+	// コメント
+	COMMENT = "COMMENT"
+
 	//  識別子
 	IDENT = "IDENT"
 
 	// 数値リテラル
-	INT = "INT"
+	INT   = "INT"
+	FLOAT = "FLOAT"
 
 	// 文字列リテラル
 	STRING = "STRING"
@@ -27,10 +33,29 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 	EQ       = "=="
 	NOT_EQ   = "!="
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
+	AND      = "&&"
+	OR       = "||"
+
+	// 複合代入演算子
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
+	// ビット演算子
+	AMPERSAND   = "&"
+	PIPE        = "|"
+	CARET       = "^"
+	TILDE       = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 
 	// デリミタ
 	COMMA     = ","
@@ -43,6 +68,8 @@ const (
 	LBRACKET = "["
 	RBRACKET = "]"
 	COLON    = ":"
+	ELLIPSIS = "..."
+	ARROW    = "=>"
 
 	// キーワード
 	FUNCTION = "FUNCTION"
@@ -53,17 +80,29 @@ const (
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
 	MACRO    = "MACRO"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	MATCH    = "MATCH"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"macro":  MACRO,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"macro":    MACRO,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
 }
 
 func LookupIdent(ident string) TokenType {